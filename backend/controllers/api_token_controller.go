@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pastebin/database"
+	"pastebin/middleware"
+	"pastebin/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateAPITokenHandler handles creating a personal access token for the current user
+func CreateAPITokenHandler(c *gin.Context) {
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+	role := middleware.GetRole(c)
+	for _, scope := range req.Scopes {
+		if !models.IsValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope " + scope})
+			return
+		}
+		if !models.RoleAllowsScope(role, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your role cannot grant scope " + scope})
+			return
+		}
+	}
+
+	expiresAt, err := models.ParseExpiration(req.ExpiresIn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plaintext, hash, err := models.GenerateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	token := models.APIToken{
+		UserID:    middleware.GetUserID(c),
+		Name:      req.Name,
+		TokenHash: hash,
+		Prefix:    plaintext[:len(models.APITokenPrefix)+6],
+		Scopes:    strings.Join(req.Scopes, ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := database.CreateAPIToken(&token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The plaintext is only ever returned here
+	c.JSON(http.StatusOK, gin.H{
+		"token":     plaintext,
+		"api_token": token,
+	})
+}
+
+// GetAPITokensHandler handles listing the personal access tokens of the current user
+func GetAPITokensHandler(c *gin.Context) {
+	tokens, err := database.GetAPITokensByUser(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// DeleteAPITokenHandler handles revoking a personal access token of the current user
+func DeleteAPITokenHandler(c *gin.Context) {
+	tokenID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	err = database.DeleteAPIToken(tokenID, middleware.GetUserID(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
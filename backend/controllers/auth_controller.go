@@ -1,18 +1,19 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"pastebin/database"
 	"pastebin/middleware"
 	"pastebin/models"
+	"pastebin/token"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-var jwtSecret = []byte("your-secret-key")
-
 // LoginHandler handles user login
 func LoginHandler(c *gin.Context) {
 	var loginReq models.LoginRequest
@@ -21,35 +22,33 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	// Get credentials from environment
-	validUsername, validPassword := middleware.GetCredentials()
-
-	// Validate credentials
-	if loginReq.Username != validUsername || loginReq.Password != validPassword {
+	// Validate credentials against local users
+	user, err := database.GetLocalUserByUsername(loginReq.Username)
+	if err != nil || !user.CheckPassword(loginReq.Password) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Create JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": loginReq.Username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-	})
-
-	tokenString, err := token.SignedString(jwtSecret)
-	if err != nil {
+	// Create session and JWT token
+	if err := startSession(c, user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
 		return
 	}
 
-	// Set token as HTTP-only cookie with proper settings
-	c.SetCookie("token", tokenString, 3600*24, "/", "", false, true)
-
 	c.JSON(http.StatusOK, gin.H{"message": "Login successful"})
 }
 
 // LogoutHandler handles user logout
 func LogoutHandler(c *gin.Context) {
+	// Revoke the session so the token stops working even if it was copied
+	if sessionID := middleware.GetSessionID(c); sessionID != "" {
+		err := database.RevokeSession(sessionID, middleware.GetUserID(c))
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// Clear the token cookie
 	c.SetCookie("token", "", -1, "/", "", false, true)
 	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
@@ -63,14 +62,64 @@ func CheckAuthHandler(c *gin.Context) {
 		return
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+	claims, err := middleware.ParseSessionToken(tokenString)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"authenticated": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authenticated": true,
+		"username":      claims.Username,
+		"role":          claims.Role,
 	})
+}
 
-	if err != nil || !token.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"authenticated": false})
+// RotateJWTKeyHandler handles generating a new JWT signing key.
+// Tokens signed with previous keys stay valid until they expire.
+func RotateJWTKeyHandler(c *gin.Context) {
+	kid, err := token.Rotate()
+	if err != nil {
+		if errors.Is(err, token.ErrKeysFromEnv) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"authenticated": true})
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "JWT signing key rotated successfully",
+		"active_kid": kid,
+	})
+}
+
+// startSession records a server-side session for the user and sets the JWT cookie bound to it
+func startSession(c *gin.Context, user *models.User) error {
+	sessionID, err := token.NewSessionID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	session := models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(token.Lifetime),
+	}
+	if err := database.CreateSession(&session); err != nil {
+		return err
+	}
+
+	tokenString, err := token.NewUserToken(user, sessionID)
+	if err != nil {
+		return err
+	}
+
+	// Set token as HTTP-only cookie with proper settings
+	c.SetCookie("token", tokenString, int(token.Lifetime.Seconds()), "/", "", false, true)
+	return nil
 }
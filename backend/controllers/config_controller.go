@@ -6,6 +6,7 @@ import (
 
 	"pastebin/database"
 	"pastebin/models"
+	"pastebin/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -65,6 +66,12 @@ func UpdateAIConfigHandler(c *gin.Context) {
 		return
 	}
 
+	// Older clients do not send a provider, keep the current one then
+	if aiConfig.Provider != "" && !services.IsSupportedAIProvider(aiConfig.Provider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported AI provider"})
+		return
+	}
+
 	// Update each AI configuration setting
 	configs := map[string]string{
 		"ai_enabled":     boolToString(aiConfig.Enabled),
@@ -76,6 +83,10 @@ func UpdateAIConfigHandler(c *gin.Context) {
 		"ai_temperature": aiConfig.Temperature,
 	}
 
+	if aiConfig.Provider != "" {
+		configs["ai_provider"] = aiConfig.Provider
+	}
+
 	for key, value := range configs {
 		err := database.UpdateConfig(key, value)
 		if err != nil {
@@ -135,6 +146,7 @@ func GetAIConfigHandler(c *gin.Context) {
 	}
 
 	aiConfig.Enabled = stringToBool(configMap["ai_enabled"])
+	aiConfig.Provider = configMap["ai_provider"]
 	aiConfig.BaseURL = configMap["ai_base_url"]
 	aiConfig.APIKey = configMap["ai_api_key"]
 	aiConfig.Model = configMap["ai_model"]
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pastebin/database"
+	"pastebin/models"
+	"pastebin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DiffHandler handles computing a diff between two pastes or paste revisions
+func DiffHandler(c *gin.Context) {
+	result, status, err := computeDiff(c, c.Query("a"), c.Query("b"))
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RawDiffHandler handles computing a diff rendered as a unified diff
+func RawDiffHandler(c *gin.Context) {
+	c.Header("Access-Control-Allow-Origin", "*")
+
+	result, status, err := computeDiff(c, c.Param("a"), c.Param("b"))
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			c.String(status, "Internal server error")
+		} else {
+			c.String(status, err.Error())
+		}
+		return
+	}
+
+	c.Header("Content-Type", "text/x-diff; charset=utf-8")
+	c.String(http.StatusOK, services.NewDiffService().FormatUnified(*result))
+}
+
+// computeDiff loads both sides referenced as <id>[@rev] and diffs them
+func computeDiff(c *gin.Context, refA, refB string) (*models.DiffResult, int, error) {
+	if refA == "" || refB == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("both a and b must be specified")
+	}
+
+	sideA, contentA, status, err := loadDiffSide(c, refA)
+	if err != nil {
+		return nil, status, err
+	}
+	sideB, contentB, status, err := loadDiffSide(c, refB)
+	if err != nil {
+		return nil, status, err
+	}
+
+	hunks := services.NewDiffService().Diff(contentA, contentB)
+	result := &models.DiffResult{
+		A:         *sideA,
+		B:         *sideB,
+		Identical: len(hunks) == 0,
+		Hunks:     hunks,
+	}
+	if result.Hunks == nil {
+		result.Hunks = []models.DiffHunk{}
+	}
+
+	return result, http.StatusOK, nil
+}
+
+// loadDiffSide resolves a <id>[@rev] reference into a paste revision
+func loadDiffSide(c *gin.Context, ref string) (*models.DiffSide, string, int, error) {
+	randomID, revision := ref, 0
+	if at := strings.LastIndex(ref, "@"); at >= 0 {
+		randomID = ref[:at]
+		rev, err := strconv.Atoi(ref[at+1:])
+		if err != nil || rev <= 0 {
+			return nil, "", http.StatusBadRequest, fmt.Errorf("invalid revision in %q", ref)
+		}
+		revision = rev
+	}
+
+	paste, err := loadReadablePaste(c, randomID)
+	if err != nil {
+		status, message := pasteErrorResponse(err)
+		return nil, "", status, fmt.Errorf("%s: %s", randomID, message)
+	}
+	// The server only holds ciphertext for encrypted pastes, a diff of it is meaningless
+	if !paste.CanInspectContent() {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("%s: encrypted pastes cannot be compared", randomID)
+	}
+
+	rev, err := database.GetPasteRevision(randomID, revision)
+	if err != nil {
+		status, message := pasteErrorResponse(err)
+		return nil, "", status, fmt.Errorf("%s: %s", randomID, message)
+	}
+
+	side := &models.DiffSide{
+		RandomID: randomID,
+		Revision: rev.Revision,
+		Title:    rev.Title,
+	}
+	return side, rev.Content, http.StatusOK, nil
+}
@@ -3,13 +3,13 @@ package controllers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"net/http"
-	"time"
 
+	"pastebin/database"
 	"pastebin/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // OAuth2LoginHandler handles OAuth2 login initiation
@@ -68,14 +68,14 @@ func OAuth2CallbackHandler(c *gin.Context) {
 	}
 
 	// Exchange code for token
-	token, err := oauth2Service.ExchangeToken(code)
+	oauthToken, err := oauth2Service.ExchangeToken(code)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange token"})
 		return
 	}
 
 	// Get user info
-	userInfo, err := oauth2Service.GetUserInfo(token)
+	userInfo, err := oauth2Service.GetUserInfo(oauthToken)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
 		return
@@ -93,21 +93,26 @@ func OAuth2CallbackHandler(c *gin.Context) {
 		username = "oauth2_user"
 	}
 
-	// Create JWT token for our application
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
-		"oauth2":   true,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-	})
+	// Use the provider's stable account ID when available, usernames can change
+	providerID := username
+	if id, ok := userInfo["id"]; ok && id != nil {
+		providerID = fmt.Sprint(id)
+	} else if sub, ok := userInfo["sub"].(string); ok {
+		providerID = sub
+	}
 
-	tokenString, err := jwtToken.SignedString(jwtSecret)
+	// Link the OAuth2 account to a local user record, creating it on first login
+	user, err := database.GetOrCreateOAuth2User(providerID, username)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	// Set token as HTTP-only cookie
-	c.SetCookie("token", tokenString, 3600*24, "/", "", false, true)
+	// Create session and JWT token for our application
+	if err := startSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create token"})
+		return
+	}
 
 	// Redirect to home page
 	c.Redirect(http.StatusTemporaryRedirect, "/")
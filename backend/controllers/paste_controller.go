@@ -1,34 +1,233 @@
 package controllers
 
 import (
-	"database/sql"
+	"errors"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"pastebin/database"
+	"pastebin/middleware"
 	"pastebin/models"
+	"pastebin/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"gorm.io/gorm"
 )
 
+// maxPastePasswordLength is the longest password bcrypt can hash
+const maxPastePasswordLength = 72
+
+// Errors returned when reading a password protected paste
+var (
+	errPastePasswordRequired  = errors.New("password required")
+	errPastePasswordIncorrect = errors.New("incorrect password")
+	errPastePasswordLocked    = errors.New("too many incorrect passwords")
+)
+
+// pastePasswordLimiter limits wrong password attempts per client and paste, so guessing
+// from one client does not lock everybody else out of the paste
+var pastePasswordLimiter = services.NewAttemptLimiter(5, 15*time.Minute)
+
+// pasteErrorResponse maps a paste lookup error to an HTTP status and message
+func pasteErrorResponse(err error) (int, string) {
+	switch {
+	case errors.Is(err, database.ErrPasteExpired):
+		return http.StatusGone, "Paste has expired"
+	case errors.Is(err, database.ErrPasteBurned):
+		return http.StatusGone, "This paste has been burned after reading"
+	case errors.Is(err, database.ErrNotPasteOwner):
+		return http.StatusForbidden, "You do not own this paste"
+	case errors.Is(err, database.ErrPasteViewLimited):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, database.ErrEditConflict):
+		return http.StatusConflict, "Paste was edited concurrently, reload it and try again"
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound, "Paste not found"
+	case errors.Is(err, errPastePasswordRequired):
+		return http.StatusUnauthorized, "Password required"
+	case errors.Is(err, errPastePasswordIncorrect):
+		return http.StatusUnauthorized, "Incorrect password"
+	case errors.Is(err, errPastePasswordLocked):
+		return http.StatusTooManyRequests, "Too many incorrect passwords, try again later"
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+// writePasteError writes a paste lookup error as JSON. Password errors carry a
+// password_required marker so the view page knows to prompt for it.
+func writePasteError(c *gin.Context, err error) {
+	status, message := pasteErrorResponse(err)
+	response := gin.H{"error": message}
+	if isPastePasswordError(err) {
+		c.Header("X-Paste-Password-Required", "true")
+		response["password_required"] = true
+	}
+	c.JSON(status, response)
+}
+
+// isPastePasswordError reports whether the error means the paste password is missing or wrong
+func isPastePasswordError(err error) bool {
+	return errors.Is(err, errPastePasswordRequired) ||
+		errors.Is(err, errPastePasswordIncorrect) ||
+		errors.Is(err, errPastePasswordLocked)
+}
+
+// loadReadablePaste retrieves a paste and checks that the requester may read it.
+// Private pastes are reported as missing to everyone but their owner and admins,
+// password protected pastes need the password unless read by their owner or an admin.
+func loadReadablePaste(c *gin.Context, randomID string) (*models.Paste, error) {
+	paste, err := database.GetPasteByRandomID(randomID)
+	if err != nil {
+		return nil, err
+	}
+	viewer := middleware.GetViewer(c)
+	if !viewer.CanRead(paste) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if viewer.NeedsPassword(paste) {
+		if err := checkPastePassword(c, paste); err != nil {
+			return nil, err
+		}
+	}
+	return paste, nil
+}
+
+// checkPastePassword verifies the password sent in the X-Paste-Password header or the password query parameter
+func checkPastePassword(c *gin.Context, paste *models.Paste) error {
+	password := c.GetHeader("X-Paste-Password")
+	if password == "" {
+		password = c.Query("password")
+	}
+	if password == "" {
+		return errPastePasswordRequired
+	}
+
+	key := c.ClientIP() + " " + paste.RandomID
+	if !pastePasswordLimiter.Reserve(key) {
+		return errPastePasswordLocked
+	}
+	if !paste.CheckPassword(password) {
+		return errPastePasswordIncorrect
+	}
+	pastePasswordLimiter.Release(key)
+	return nil
+}
+
+// resolvePasteLanguage fills in the language of a paste that has none yet by detecting it.
+// Encrypted pastes are never inspected and keep whatever language the client gave.
+func resolvePasteLanguage(paste *models.Paste) {
+	if paste.Language == "" && paste.CanInspectContent() {
+		paste.Language = services.NewLanguageDetector().Detect(paste.Title, paste.Content)
+	}
+}
+
+// hideProtectedContent blanks the content of listed pastes the viewer would need a password for
+func hideProtectedContent(viewer models.Viewer, pastes []models.Paste) {
+	for i := range pastes {
+		if viewer.NeedsPassword(&pastes[i]) {
+			hidePasteContent(&pastes[i])
+		}
+	}
+}
+
+// hidePasteContent blanks the content of a paste together with the AI description summarizing it
+func hidePasteContent(paste *models.Paste) {
+	paste.Content = ""
+	paste.Description = ""
+}
+
+// defaultPasteVisibility returns the configured visibility for new pastes
+func defaultPasteVisibility() string {
+	config, err := database.GetConfigByKey("paste_default_visibility")
+	if err != nil || !models.IsValidVisibility(config.Value) {
+		return models.VisibilityUnlisted
+	}
+	return config.Value
+}
+
+// secretPolicy returns the configured handling of pastes containing secrets
+func secretPolicy() string {
+	config, err := database.GetConfigByKey("paste_secret_policy")
+	if err != nil || !models.IsValidSecretPolicy(config.Value) {
+		return models.SecretPolicyPrivate
+	}
+	return config.Value
+}
+
+// applySecretPolicy scans content for secrets and applies the configured policy: the content is
+// redacted or the visibility forced to private. The findings are returned with the action taken,
+// a block action means the paste must not be saved.
+func applySecretPolicy(content, visibility *string) []models.SecretFinding {
+	policy := secretPolicy()
+	if policy == models.SecretPolicyOff {
+		return nil
+	}
+
+	scanner := services.NewSecretScanner()
+	findings := scanner.Scan(*content)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	switch policy {
+	case models.SecretPolicyRedact:
+		*content = scanner.Redact(*content, findings)
+	case models.SecretPolicyPrivate:
+		*visibility = models.VisibilityPrivate
+	}
+	for i := range findings {
+		findings[i].Action = policy
+	}
+	return findings
+}
+
+// isSecretBlocked reports whether findings returned by applySecretPolicy reject the paste
+func isSecretBlocked(findings []models.SecretFinding) bool {
+	return len(findings) > 0 && findings[0].Action == models.SecretPolicyBlock
+}
+
+// keepsSecrets reports whether findings returned by applySecretPolicy were left in the saved
+// content. Such pastes are not sent to the AI, which would hand the secrets to the provider.
+func keepsSecrets(findings []models.SecretFinding) bool {
+	return len(findings) > 0 && findings[0].Action != models.SecretPolicyRedact
+}
+
+// writeSecretBlocked rejects a paste containing secrets, the findings tell the user what to remove
+func writeSecretBlocked(c *gin.Context, ownerID int, findings []models.SecretFinding) {
+	services.RecordSecretFindings(&models.Paste{OwnerID: &ownerID}, findings)
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":           "Paste contains secrets",
+		"secret_findings": findings,
+	})
+}
+
 // ViewPasteHandler handles the short link routes
 func ViewPasteHandler(c *gin.Context) {
 	randomID := c.Param("id")
 
-	_, err := database.GetPasteByRandomID(randomID)
+	paste, err := loadReadablePaste(c, randomID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.File("../frontend/view.html") // Serve the main page if paste not found
-			return
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// Serve the view page for missing or expired pastes, it renders the API error itself
+		if status, message := pasteErrorResponse(err); status == http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": message})
 			return
 		}
 	}
 
-	// 直接返回前端页面，让前端通过路径参数获取粘贴ID
-	c.File("../frontend/view.html")
+	// Encrypted pastes are decrypted in the browser, and view limited pastes must not be
+	// used up by link previews, both are left to the view page
+	if err != nil || !paste.CanInspectContent() || paste.MaxViews > 0 {
+		// 直接返回前端页面，让前端通过路径参数获取粘贴ID
+		c.File("../frontend/view.html")
+		return
+	}
+
+	renderPastePage(c, randomID)
 }
 
 // CreatePasteHandler handles paste creation API
@@ -42,13 +241,122 @@ func CreatePasteHandler(c *gin.Context) {
 	// Initialize AI fields
 	paste.AITitleGenerated = false
 	paste.AIRetryCount = 0
+	paste.AIClassified = false
+	paste.Description = ""
+
+	// Resolve requested lifetime into an absolute expiration time
+	expiresAt, err := models.ParseExpiration(paste.ExpiresIn)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	paste.ExpiresAt = expiresAt
+	paste.ExpiresIn = ""
+
+	// The paste belongs to the user creating it
+	ownerID := middleware.GetUserID(c)
+	paste.OwnerID = &ownerID
+	paste.ParentID = nil
+
+	// Fall back to the configured default visibility
+	if paste.Visibility == "" {
+		paste.Visibility = defaultPasteVisibility()
+	} else if !models.IsValidVisibility(paste.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visibility"})
+		return
+	}
+
+	// Protect the paste with a password if one was given, only its hash is stored
+	if len(paste.Password) > maxPastePasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at most 72 bytes"})
+		return
+	}
+	if err := paste.SetPassword(paste.Password); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	paste.Password = ""
+
+	// Encrypted pastes only carry ciphertext, the format tells clients how to decrypt it
+	if paste.Encrypted {
+		if !models.IsSupportedEncryption(paste.EncryptionFormat, paste.EncryptionVersion) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported encryption format"})
+			return
+		}
+	} else {
+		paste.EncryptionFormat = ""
+		paste.EncryptionVersion = 0
+	}
+
+	// A custom slug replaces the generated short link
+	if paste.Slug != "" {
+		if err := models.ValidateSlug(paste.Slug); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Use the given language, or detect it from the title and content
+	if paste.Language != "" {
+		language, ok := models.NormalizeLanguage(paste.Language)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+			return
+		}
+		paste.Language = language
+	}
+	resolvePasteLanguage(&paste)
+
+	// Tags are stored lowercased and deduplicated
+	tags, err := models.NormalizeTagNames(paste.TagNames())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	paste.Tags = models.TagsFromNames(tags)
+
+	// Secrets are blocked, redacted or force the paste private, encrypted content cannot be scanned
+	var findings []models.SecretFinding
+	if paste.CanInspectContent() {
+		findings = applySecretPolicy(&paste.Content, &paste.Visibility)
+		if isSecretBlocked(findings) {
+			writeSecretBlocked(c, ownerID, findings)
+			return
+		}
+	}
+
+	// Every paste starts at its first revision
+	paste.Revision = 1
+	paste.EditedAt = nil
+
+	// Burn after read is a single allowed view
+	paste.ViewCount = 0
+	if paste.MaxViews < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_views must not be negative"})
+		return
+	}
+	if paste.BurnAfterRead {
+		paste.MaxViews = 1
+	}
 
 	// Insert paste into database
-	err := database.CreatePaste(&paste)
+	err = database.CreatePaste(&paste)
+	if errors.Is(err, database.ErrSlugTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Slug is already taken"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if !keepsSecrets(findings) {
+		services.EnqueuePasteClassification(&paste)
+	}
+
+	if len(findings) > 0 {
+		services.RecordSecretFindings(&paste, findings)
+		paste.SecretFindings = findings
+	}
 
 	c.JSON(http.StatusOK, paste)
 }
@@ -57,22 +365,324 @@ func CreatePasteHandler(c *gin.Context) {
 func GetPasteHandler(c *gin.Context) {
 	randomID := c.Param("id")
 
-	paste, err := database.GetPasteByRandomID(randomID)
+	_, err := loadReadablePaste(c, randomID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Paste not found"})
-		} else {
+		writePasteError(c, err)
+		return
+	}
+
+	paste, err := database.ViewPasteByRandomID(randomID)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+	resolvePasteLanguage(paste)
+
+	// Markdown pastes also carry their sanitized rendering
+	if paste.IsMarkdown() {
+		rendered, err := services.NewMarkdownService().Render(paste.Content)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
+		paste.Rendered = rendered
+	}
+
+	c.JSON(http.StatusOK, paste)
+}
+
+// UpdatePasteHandler handles editing a paste while keeping its short link
+func UpdatePasteHandler(c *gin.Context) {
+	randomID := c.Param("id")
+
+	var req models.UpdatePasteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if req.Title == nil && req.Content == nil && req.Visibility == nil && req.Password == nil && req.Language == nil && req.Tags == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+	if req.Visibility != nil && !models.IsValidVisibility(*req.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid visibility"})
+		return
+	}
+	if req.Password != nil && len(*req.Password) > maxPastePasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at most 72 bytes"})
+		return
+	}
+	if req.Language != nil && *req.Language != "" {
+		language, ok := models.NormalizeLanguage(*req.Language)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language"})
+			return
+		}
+		req.Language = &language
+	}
+	if req.Tags != nil {
+		tags, err := models.NormalizeTagNames(*req.Tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Tags = &tags
+	}
+	if req.Content != nil && *req.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content cannot be empty"})
+		return
+	}
+
+	// New content is checked for secrets like on creation. A visibility change checks the
+	// stored content again, so a paste forced private cannot simply be made public.
+	userID := middleware.GetUserID(c)
+	var findings []models.SecretFinding
+	if req.Content != nil || req.Visibility != nil {
+		existing, err := database.GetPasteByRandomID(randomID)
+		if err == nil && !existing.IsOwnedBy(userID) {
+			err = database.ErrNotPasteOwner
+		}
+		if err != nil {
+			writePasteError(c, err)
+			return
+		}
+
+		visibilityChanged := req.Visibility != nil && *req.Visibility != existing.Visibility
+		if existing.CanInspectContent() && (req.Content != nil || visibilityChanged) {
+			content := existing.Content
+			if req.Content != nil {
+				content = *req.Content
+			}
+			visibility := existing.Visibility
+			if req.Visibility != nil {
+				visibility = *req.Visibility
+			}
+			findings = applySecretPolicy(&content, &visibility)
+			if isSecretBlocked(findings) {
+				writeSecretBlocked(c, userID, findings)
+				return
+			}
+			if req.Content != nil || content != existing.Content {
+				req.Content = &content
+			}
+			if req.Visibility != nil || visibility != existing.Visibility {
+				req.Visibility = &visibility
+			}
+		}
+	}
+
+	paste, err := database.UpdatePasteByRandomID(randomID, userID, req)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+	resolvePasteLanguage(paste)
+
+	if len(findings) > 0 {
+		services.RecordSecretFindings(paste, findings)
+		paste.SecretFindings = findings
+	}
+
+	// New content is classified again, and classification skips pastes with a password,
+	// it catches up once the password is removed
+	if req.Content != nil || (req.Password != nil && *req.Password == "") {
+		services.RequeuePasteClassification(paste.ID)
+	}
+
 	c.JSON(http.StatusOK, paste)
 }
 
+// GetPasteRevisionsHandler handles listing all versions of a paste
+func GetPasteRevisionsHandler(c *gin.Context) {
+	randomID := c.Param("id")
+
+	if _, err := loadReadablePaste(c, randomID); err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	revisions, err := database.GetPasteRevisions(randomID)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"count":     len(revisions),
+	})
+}
+
+// GetPasteRevisionHandler handles retrieval of a specific paste version
+func GetPasteRevisionHandler(c *gin.Context) {
+	randomID := c.Param("id")
+
+	revision, err := strconv.Atoi(c.Param("n"))
+	if err != nil || revision <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	if _, err := loadReadablePaste(c, randomID); err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	rev, err := database.GetPasteRevision(randomID, revision)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+// ForkPasteHandler handles copying a paste into a new paste that records its origin
+func ForkPasteHandler(c *gin.Context) {
+	randomID := c.Param("id")
+
+	original, err := loadReadablePaste(c, randomID)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	source, err := database.GetPasteRevision(randomID, 0)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	// The fork keeps the visibility and password of its source so protected content stays protected
+	ownerID := middleware.GetUserID(c)
+	paste := models.Paste{
+		Title:             source.Title,
+		Description:       original.Description,
+		AIClassified:      original.AIClassified,
+		Content:           source.Content,
+		Revision:          1,
+		ParentID:          &source.PasteID,
+		OwnerID:           &ownerID,
+		Visibility:        original.Visibility,
+		PasswordHash:      original.PasswordHash,
+		PasswordProtected: original.PasswordProtected,
+		Encrypted:         original.Encrypted,
+		EncryptionFormat:  original.EncryptionFormat,
+		EncryptionVersion: original.EncryptionVersion,
+		Language:          original.Language,
+		Tags:              models.TagsFromNames(original.TagNames()),
+	}
+
+	err = database.CreatePaste(&paste)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The fork copies the content as saved, secrets the policy kept in it are recorded
+	// for the fork as well and keep it away from the AI
+	var findings []models.SecretFinding
+	if policy := secretPolicy(); paste.CanInspectContent() && policy != models.SecretPolicyOff && policy != models.SecretPolicyRedact {
+		findings = services.NewSecretScanner().Scan(paste.Content)
+		for i := range findings {
+			findings[i].Action = policy
+		}
+	}
+	if len(findings) > 0 {
+		services.RecordSecretFindings(&paste, findings)
+	} else {
+		services.EnqueuePasteClassification(&paste)
+	}
+
+	c.JSON(http.StatusOK, paste)
+}
+
+// ExplainPasteHandler handles asking the AI to explain the current revision of a paste
+func ExplainPasteHandler(c *gin.Context) {
+	paste, err := loadReadablePaste(c, c.Param("id"))
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	// The server only holds ciphertext of encrypted pastes, and explaining a view
+	// limited paste would reveal its content without using up a view
+	if !paste.CanInspectContent() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Encrypted pastes cannot be explained"})
+		return
+	}
+	if paste.MaxViews > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pastes with a view limit cannot be explained"})
+		return
+	}
+
+	explanation, err := services.NewAIService().ExplainPaste(paste)
+	switch {
+	case errors.Is(err, services.ErrAINotEnabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI is not enabled"})
+		return
+	case errors.Is(err, services.ErrAIBudgetExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "AI daily budget exceeded, try again tomorrow"})
+		return
+	case errors.Is(err, services.ErrPasteKeepsSecrets):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pastes containing secrets cannot be explained"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, explanation)
+}
+
+// GetPasteForksHandler handles retrieval of a paste's parent and forks
+func GetPasteForksHandler(c *gin.Context) {
+	randomID := c.Param("id")
+
+	paste, err := loadReadablePaste(c, randomID)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+
+	allForks, err := database.GetPasteForks(paste.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewer := middleware.GetViewer(c)
+	forks := []models.Paste{}
+	for i := range allForks {
+		if viewer.CanRead(&allForks[i]) {
+			forks = append(forks, allForks[i])
+		}
+	}
+
+	// The parent may have expired or been deleted since, only link to it while it is readable
+	var parent *models.Paste
+	if paste.ParentID != nil {
+		parent, err = database.GetPasteByID(strconv.Itoa(*paste.ParentID))
+		if err != nil || parent.IsExpired() || parent.IsBurned() || !viewer.CanRead(parent) {
+			parent = nil
+		}
+	}
+	hideProtectedContent(viewer, forks)
+	if parent != nil && viewer.NeedsPassword(parent) {
+		hidePasteContent(parent)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"parent": parent,
+		"forks":  forks,
+		"count":  len(forks),
+	})
+}
+
 // GetAllPastesHandler handles retrieval of all pastes
 func GetAllPastesHandler(c *gin.Context) {
-	pastes, err := database.GetAllPastes()
+	pastes, err := database.GetAllPastes(middleware.GetUserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -81,9 +691,8 @@ func GetAllPastesHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, pastes)
 }
 
-// GetPastesWithPaginationHandler handles retrieval of pastes with pagination
-func GetPastesWithPaginationHandler(c *gin.Context) {
-	// Get page and pageSize from query parameters
+// parsePagination reads the page and page_size query parameters
+func parsePagination(c *gin.Context) (int, int) {
 	page := 1
 	pageSize := 10
 
@@ -99,37 +708,94 @@ func GetPastesWithPaginationHandler(c *gin.Context) {
 		}
 	}
 
-	pastes, totalCount, err := database.GetPastesWithPagination(page, pageSize)
+	return page, pageSize
+}
+
+// paginatedResponse builds the envelope shared by the paginated paste listings
+func paginatedResponse(pastes interface{}, page, pageSize, totalCount int) gin.H {
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
+
+	return gin.H{
+		"pastes":       pastes,
+		"current_page": page,
+		"page_size":    pageSize,
+		"total_count":  totalCount,
+		"total_pages":  totalPages,
+	}
+}
+
+// GetPastesWithPaginationHandler handles retrieval of pastes with pagination
+func GetPastesWithPaginationHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+	tags, err := parseTagFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pastes, totalCount, err := database.GetPastesWithPagination(middleware.GetUserID(c), tags, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Calculate total pages
-	totalPages := int(math.Ceil(float64(totalCount) / float64(pageSize)))
+	c.JSON(http.StatusOK, paginatedResponse(pastes, page, pageSize, totalCount))
+}
 
-	response := gin.H{
-		"pastes":      pastes,
-		"current_page": page,
-		"page_size":   pageSize,
-		"total_count": totalCount,
-		"total_pages": totalPages,
+// maxSearchQueryLength bounds the length of a full-text search query
+const maxSearchQueryLength = 256
+
+// SearchPastesHandler handles full-text search over the user's pastes
+func SearchPastesHandler(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is too long"})
+		return
+	}
+	page, pageSize := parsePagination(c)
+
+	results, totalCount, err := database.SearchPastes(middleware.GetUserID(c), query, page, pageSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidSearchQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, database.ErrSearchUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, paginatedResponse(results, page, pageSize, totalCount))
+}
+
+// GetPublicPastesHandler handles retrieval of publicly listed pastes with pagination
+func GetPublicPastesHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+
+	pastes, totalCount, err := database.GetPublicPastesWithPagination(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	hideProtectedContent(middleware.GetViewer(c), pastes)
+
+	c.JSON(http.StatusOK, paginatedResponse(pastes, page, pageSize, totalCount))
 }
 
 // DeletePasteHandler handles paste deletion
 func DeletePasteHandler(c *gin.Context) {
 	randomID := c.Param("id")
 
-	err := database.DeletePasteByRandomID(randomID)
+	err := database.DeletePasteByRandomID(randomID, middleware.GetUserID(c))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Paste not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		writePasteError(c, err)
 		return
 	}
 
@@ -139,21 +805,62 @@ func DeletePasteHandler(c *gin.Context) {
 // GetRawPasteHandler handles raw paste retrieval
 func GetRawPasteHandler(c *gin.Context) {
 	randomID := c.Param("id")
+	c.Header("Access-Control-Allow-Origin", "*")
 
-	paste, err := database.GetPasteByRandomID(randomID)
+	original, err := loadReadablePaste(c, randomID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			c.Header("Access-Control-Allow-Origin", "*")
-			c.String(http.StatusNotFound, "Paste not found")
-		} else {
-			c.Header("Access-Control-Allow-Origin", "*")
-			c.String(http.StatusInternalServerError, "Internal server error")
+		writeRawPasteError(c, err)
+		return
+	}
+
+	var content string
+	if rev := c.Query("rev"); rev != "" {
+		// A specific version was requested
+		revision, err := strconv.Atoi(rev)
+		if err != nil || revision <= 0 {
+			c.String(http.StatusBadRequest, "Invalid revision number")
+			return
+		}
+
+		pasteRevision, err := database.GetPasteRevision(randomID, revision)
+		if err != nil {
+			writeRawPasteError(c, err)
+			return
 		}
+		content = pasteRevision.Content
+	} else {
+		paste, err := database.ViewPasteByRandomID(randomID)
+		if err != nil {
+			writeRawPasteError(c, err)
+			return
+		}
+		content = paste.Content
+	}
+
+	// Encrypted pastes are served as the ciphertext envelope for clients holding the key
+	if original.Encrypted {
+		envelope := original.Envelope()
+		envelope.Ciphertext = content
+		c.Header("Content-Type", models.EncryptedContentType)
+		c.Render(http.StatusOK, render.JSON{Data: envelope})
 		return
 	}
 
-	// Set headers for raw text response
-	c.Header("Content-Type", "text/plain; charset=utf-8")
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.String(http.StatusOK, paste.Content)
+	// The content type follows the paste language, browsers must not sniff it into something else
+	resolvePasteLanguage(original)
+	c.Header("Content-Type", models.LanguageContentType(original.Language))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.String(http.StatusOK, content)
+}
+
+// writeRawPasteError writes a paste lookup error as plain text
+func writeRawPasteError(c *gin.Context, err error) {
+	status, message := pasteErrorResponse(err)
+	if isPastePasswordError(err) {
+		c.Header("X-Paste-Password-Required", "true")
+	}
+	if status == http.StatusInternalServerError {
+		message = "Internal server error"
+	}
+	c.String(status, message)
 }
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"pastebin/database"
+	"pastebin/models"
+	"pastebin/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server rendered page templates
+const (
+	pastePageTemplate    = "../frontend/paste.html"
+	markdownPageTemplate = "../frontend/markdown.html"
+)
+
+// maxDescriptionLength bounds the content excerpt shown in link previews
+const maxDescriptionLength = 200
+
+// pastePage is the data rendered into the paste page template
+type pastePage struct {
+	Title       string
+	Description string
+	Lines       int
+	Paste       *models.Paste
+	Highlight   pageHighlight
+}
+
+// pageHighlight carries the rendered code into the template without escaping it again
+type pageHighlight struct {
+	HTML     template.HTML
+	CSS      template.CSS
+	Theme    string
+	Language string
+}
+
+// markdownPage is the data rendered into the Markdown page template
+type markdownPage struct {
+	Title       string
+	Description string
+	Paste       *models.Paste
+	HTML        template.HTML
+	CSS         template.CSS
+}
+
+// MarkdownPasteHandler renders a Markdown paste as a sanitized HTML page
+func MarkdownPasteHandler(c *gin.Context) {
+	randomID := c.Param("id")
+
+	paste, err := loadReadablePaste(c, randomID)
+	if err != nil {
+		writeRawPasteError(c, err)
+		return
+	}
+	resolvePasteLanguage(paste)
+	if !paste.IsMarkdown() {
+		// Anything else is shown by the regular paste page
+		c.Redirect(http.StatusFound, "/"+randomID)
+		return
+	}
+
+	paste, err = database.ViewPasteByRandomID(randomID)
+	if err != nil {
+		writeRawPasteError(c, err)
+		return
+	}
+
+	highlightService := services.NewHighlightService()
+	rendered, err := services.NewMarkdownService().Render(paste.Content)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	css, err := highlightService.ThemeCSS(highlightService.ResolveTheme(c.Query("theme")))
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writePage(c, markdownPageTemplate, markdownPage{
+		Title:       pageTitle(paste),
+		Description: pageDescription(paste),
+		Paste:       paste,
+		// The Markdown service sanitizes its output
+		HTML: template.HTML(rendered),
+		CSS:  template.CSS(css),
+	})
+}
+
+// GetPasteHTMLHandler returns a paste rendered as syntax highlighted HTML for embedding
+func GetPasteHTMLHandler(c *gin.Context) {
+	randomID := c.Param("id")
+	c.Header("Access-Control-Allow-Origin", "*")
+
+	paste, err := loadReadablePaste(c, randomID)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+	if !paste.CanInspectContent() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Encrypted pastes cannot be rendered on the server"})
+		return
+	}
+
+	options, err := highlightOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paste, err = database.ViewPasteByRandomID(randomID)
+	if err != nil {
+		writePasteError(c, err)
+		return
+	}
+	resolvePasteLanguage(paste)
+
+	result, err := services.NewHighlightService().Highlight(paste.Content, paste.Language, options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// renderPastePage writes the server rendered page for a paste, counting it as a view
+func renderPastePage(c *gin.Context, randomID string) {
+	options, err := highlightOptions(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	paste, err := database.ViewPasteByRandomID(randomID)
+	if err != nil {
+		writeRawPasteError(c, err)
+		return
+	}
+	resolvePasteLanguage(paste)
+
+	result, err := services.NewHighlightService().Highlight(paste.Content, paste.Language, options)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	writePage(c, pastePageTemplate, pastePage{
+		Title:       pageTitle(paste),
+		Description: pageDescription(paste),
+		Lines:       strings.Count(strings.TrimSuffix(paste.Content, "\n"), "\n") + 1,
+		Paste:       paste,
+		Highlight: pageHighlight{
+			// chroma escapes the paste content itself
+			HTML:     template.HTML(result.HTML),
+			CSS:      template.CSS(result.CSS),
+			Theme:    result.Theme,
+			Language: result.Language,
+		},
+	})
+}
+
+// writePage renders a page template, nothing is written if rendering fails halfway
+func writePage(c *gin.Context, file string, data interface{}) {
+	tmpl, err := template.ParseFiles(file)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// pageTitle returns the title shown for a paste
+func pageTitle(paste *models.Paste) string {
+	if strings.TrimSpace(paste.Title) == "" {
+		return "Untitled Paste"
+	}
+	return paste.Title
+}
+
+// highlightOptions reads the theme, line_numbers and lines query parameters
+func highlightOptions(c *gin.Context) (services.HighlightOptions, error) {
+	options := services.HighlightOptions{
+		Theme:       c.Query("theme"),
+		LineNumbers: c.Query("line_numbers") != "false",
+	}
+	if lines := c.Query("lines"); lines != "" {
+		ranges, err := services.ParseLineRanges(lines)
+		if err != nil {
+			return options, err
+		}
+		options.HighlightLines = ranges
+	}
+	return options, nil
+}
+
+// pageDescription returns the link preview text, the AI description if there is one
+func pageDescription(paste *models.Paste) string {
+	if paste.Description != "" {
+		return excerpt(paste.Description, maxDescriptionLength)
+	}
+	return excerpt(paste.Content, maxDescriptionLength)
+}
+
+// excerpt collapses whitespace and shortens text to at most limit characters
+func excerpt(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit]) + "…"
+}
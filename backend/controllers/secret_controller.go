@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"net/http"
+
+	"pastebin/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSecretFindingsHandler handles listing the secrets found in pastes for review by admins
+func GetSecretFindingsHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+
+	findings, totalCount, err := database.GetSecretFindings(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, paginatedResponse(findings, page, pageSize, totalCount))
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"pastebin/database"
+	"pastebin/middleware"
+	"pastebin/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTagsHandler handles listing the tags of the user's pastes with their counts
+func GetTagsHandler(c *gin.Context) {
+	tags, err := database.GetTagCounts(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// parseTagFilter reads the tag filter of a paste listing. Tags are given as repeated
+// or comma separated tag parameters, tag_match=any matches pastes with any of them
+// instead of all of them.
+func parseTagFilter(c *gin.Context) (models.TagFilter, error) {
+	var names []string
+	for _, value := range c.QueryArray("tag") {
+		names = append(names, strings.Split(value, ",")...)
+	}
+
+	filter := models.TagFilter{MatchAll: true}
+	switch c.DefaultQuery("tag_match", "all") {
+	case "all":
+	case "any":
+		filter.MatchAll = false
+	default:
+		return filter, fmt.Errorf("tag_match must be all or any")
+	}
+
+	names, err := models.NormalizeTagNames(names)
+	if err != nil {
+		return filter, err
+	}
+	filter.Names = names
+	return filter, nil
+}
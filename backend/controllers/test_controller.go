@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
+	"pastebin/database"
+	"pastebin/models"
 	"pastebin/services"
 
 	"github.com/gin-gonic/gin"
@@ -30,6 +33,7 @@ func TestAIHandler(c *gin.Context) {
 	request := services.GenerateTitleRequest{
 		Content: req.Content,
 		Created: time.Now().Format("2006-01-02 15:04"),
+		Source:  models.AIUsageSourceTest,
 	}
 	
 	response, err := aiService.GenerateTitle(request)
@@ -46,9 +50,13 @@ func TestAIHandler(c *gin.Context) {
 	if response != nil {
 		responseData["title"] = response.Title
 		responseData["desc"] = response.Desc
+		responseData["tags"] = response.Tags
+		responseData["language"] = response.Language
 	} else {
 		responseData["title"] = ""
 		responseData["desc"] = ""
+		responseData["tags"] = []string{}
+		responseData["language"] = ""
 	}
 
 	c.JSON(http.StatusOK, responseData)
@@ -68,3 +76,60 @@ func GetModelsHandler(c *gin.Context) {
 		"count":  len(models),
 	})
 }
+
+// AIHealthHandler handles checking that the configured AI provider is reachable
+func AIHealthHandler(c *gin.Context) {
+	if err := services.NewAIService().HealthCheck(); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"status": "error", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GetAIJobsHandler handles reporting the number of AI jobs in each state
+func GetAIJobsHandler(c *gin.Context) {
+	counts, err := database.GetJobCounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": counts})
+}
+
+// Range of days reported by the AI usage endpoint
+const (
+	defaultAIUsageDays = 30
+	maxAIUsageDays     = 365
+)
+
+// GetAIUsageHandler handles reporting daily AI usage and today's budget
+func GetAIUsageHandler(c *gin.Context) {
+	days := defaultAIUsageDays
+	if d := c.Query("days"); d != "" {
+		dInt, err := strconv.Atoi(d)
+		if err != nil || dInt < 1 || dInt > maxAIUsageDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+			return
+		}
+		days = dInt
+	}
+
+	usage, err := database.GetAIUsageDaily(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget, err := services.NewAIService().Budget()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":   usage,
+		"budget": budget,
+	})
+}
@@ -8,29 +8,76 @@ import (
 
 // AI processing related database functions
 
-// GetPastesForAIProcessing retrieves pastes that need AI title generation
-func GetPastesForAIProcessing() ([]models.Paste, error) {
-	var pastes []models.Paste
-	err := DB.Where("(title IS NULL OR title = '') AND ai_title_generated = ? AND ai_retry_count < ?", false, 3).
-		Order("created_at ASC").
-		Limit(10).
-		Find(&pastes).Error
-
+// GetPasteForAIProcessing retrieves a paste with its tags for classification
+func GetPasteForAIProcessing(pasteID int) (*models.Paste, error) {
+	var paste models.Paste
+	err := DB.Scopes(withTags).Where("id = ?", pasteID).First(&paste).Error
 	if err != nil {
 		return nil, err
 	}
+	return &paste, nil
+}
+
+// SavePasteClassification stores the title, description, language and tags the AI
+// produced for a paste and marks it as classified. The AI call takes a while, so the
+// result is applied to the paste as it is now: a generated title only replaces an empty
+// one, a language only replaces an empty or plaintext one, and generated tags are added
+// to the current tags, keeping whatever the owner changed in the meantime. Nothing is
+// saved when a password was set meanwhile, the paste stays unclassified.
+func SavePasteClassification(pasteID int, classification models.PasteClassification) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		// Written first, so the password check and the following updates see the same paste
+		result := tx.Model(&models.Paste{}).
+			Where("id = ? AND (password_hash IS NULL OR password_hash = '')", pasteID).
+			Updates(map[string]interface{}{
+				"description":   classification.Description,
+				"ai_classified": true,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
 
-	return pastes, nil
+		if classification.Title != "" {
+			err := tx.Model(&models.Paste{}).Where("id = ? AND (title IS NULL OR title = '')", pasteID).Updates(map[string]interface{}{
+				"title":              classification.Title,
+				"ai_title_generated": true,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if classification.Language != "" {
+			err := tx.Model(&models.Paste{}).
+				Where("id = ? AND (language IS NULL OR language = '' OR language = ?)", pasteID, models.LanguagePlaintext).
+				Update("language", classification.Language).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(classification.Tags) == 0 {
+			return nil
+		}
+		var paste models.Paste
+		if err := tx.Scopes(withTags).Where("id = ?", pasteID).First(&paste).Error; err != nil {
+			return err
+		}
+		current := paste.TagNames()
+		names := models.MergeTagNames(current, classification.Tags)
+		if len(names) == len(current) {
+			return nil
+		}
+		return replacePasteTags(tx, &paste, names)
+	})
 }
 
-// UpdatePasteAIStatus updates the AI processing status of a paste
-func UpdatePasteAIStatus(pasteID int, title string, generated bool, retryCount int) error {
-	err := DB.Model(&models.Paste{}).Where("id = ?", pasteID).Updates(map[string]interface{}{
-		"title":              title,
-		"ai_title_generated": generated,
-		"ai_retry_count":     retryCount,
-	}).Error
-	return err
+// MarkPasteClassified marks a paste as classified without changing anything else
+func MarkPasteClassified(pasteID int) error {
+	return DB.Model(&models.Paste{}).Where("id = ?", pasteID).Update("ai_classified", true).Error
 }
 
 // IncrementPasteRetryCount increments the AI retry count for a paste
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"pastebin/models"
+)
+
+func TestSavePasteClassification(t *testing.T) {
+	setupTestDB(t)
+
+	classification := models.PasteClassification{
+		Title:       "Nginx 反向代理配置",
+		Description: "把请求转发到本地服务",
+		Language:    "nginx",
+		Tags:        []string{"nginx", "proxy"},
+	}
+
+	tests := []struct {
+		name           string
+		paste          models.Paste
+		wantClassified bool
+		wantTitle      string
+		wantLanguage   string
+		wantTags       []string
+	}{
+		{
+			name:           "fills in an unclassified paste",
+			paste:          models.Paste{Content: "server {}", Language: models.LanguagePlaintext},
+			wantClassified: true,
+			wantTitle:      classification.Title,
+			wantLanguage:   "nginx",
+			wantTags:       []string{"nginx", "proxy"},
+		},
+		{
+			name: "keeps what the owner chose",
+			paste: models.Paste{Title: "my config", Content: "server {}", Language: "python",
+				Tags: models.TagsFromNames([]string{"ops", "nginx"})},
+			wantClassified: true,
+			wantTitle:      "my config",
+			wantLanguage:   "python",
+			wantTags:       []string{"nginx", "ops", "proxy"},
+		},
+		{
+			name:           "skips a paste that got a password",
+			paste:          models.Paste{Content: "server {}", PasswordHash: "hash"},
+			wantClassified: false,
+			wantTitle:      "",
+			wantLanguage:   "",
+			wantTags:       []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paste := tt.paste
+			paste.Visibility = models.VisibilityPublic
+			paste.Revision = 1
+			if err := CreatePaste(&paste); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := SavePasteClassification(paste.ID, classification); err != nil {
+				t.Fatal(err)
+			}
+
+			saved, err := GetPasteForAIProcessing(paste.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.AIClassified != tt.wantClassified {
+				t.Errorf("ai_classified = %v, want %v", saved.AIClassified, tt.wantClassified)
+			}
+			wantDescription := ""
+			if tt.wantClassified {
+				wantDescription = classification.Description
+			}
+			if saved.Description != wantDescription {
+				t.Errorf("description = %q, want %q", saved.Description, wantDescription)
+			}
+			if saved.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", saved.Title, tt.wantTitle)
+			}
+			if saved.AITitleGenerated != (tt.wantTitle != "" && tt.paste.Title == "") {
+				t.Errorf("ai_title_generated = %v", saved.AITitleGenerated)
+			}
+			if saved.Language != tt.wantLanguage {
+				t.Errorf("language = %q, want %q", saved.Language, tt.wantLanguage)
+			}
+			if tags := saved.TagNames(); !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("tags = %v, want %v", tags, tt.wantTags)
+			}
+		})
+	}
+}
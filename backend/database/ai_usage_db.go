@@ -0,0 +1,52 @@
+package database
+
+import (
+	"time"
+
+	"pastebin/models"
+)
+
+// AI usage related database functions. Days are UTC days, SQLite's date() converts
+// the stored local timestamps to UTC.
+
+// RecordAIUsage stores a call made to the AI provider
+func RecordAIUsage(usage *models.AIUsage) error {
+	return DB.Create(usage).Error
+}
+
+// GetAIUsageDaily returns the usage of the last given number of days, most recent first.
+// Days without any call are left out.
+func GetAIUsageDaily(days int) ([]models.AIUsageDay, error) {
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+
+	usage := []models.AIUsageDay{}
+	err := DB.Model(&models.AIUsage{}).
+		Select(`date(created_at) AS date,
+			COUNT(*) AS requests,
+			SUM(CASE WHEN success THEN 0 ELSE 1 END) AS failed,
+			SUM(prompt_tokens) AS prompt_tokens,
+			SUM(completion_tokens) AS completion_tokens,
+			SUM(prompt_tokens + completion_tokens) AS total_tokens,
+			AVG(latency_ms) AS avg_latency_ms`).
+		Where("date(created_at) >= ?", since).
+		Group("date(created_at)").
+		Order("date DESC").
+		Scan(&usage).Error
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// GetAIUsageToday returns the number of calls and tokens used since midnight (UTC)
+func GetAIUsageToday() (requests int64, tokens int64, err error) {
+	var row struct {
+		Requests int64
+		Tokens   int64
+	}
+	err = DB.Model(&models.AIUsage{}).
+		Select("COUNT(*) AS requests, COALESCE(SUM(prompt_tokens + completion_tokens), 0) AS tokens").
+		Where("date(created_at) = ?", time.Now().UTC().Format("2006-01-02")).
+		Scan(&row).Error
+	return row.Requests, row.Tokens, err
+}
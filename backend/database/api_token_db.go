@@ -0,0 +1,53 @@
+package database
+
+import (
+	"time"
+
+	"pastebin/models"
+
+	"gorm.io/gorm"
+)
+
+// API token related database functions
+
+// CreateAPIToken inserts a new personal access token
+func CreateAPIToken(token *models.APIToken) error {
+	return DB.Create(token).Error
+}
+
+// GetAPITokensByUser retrieves all tokens of a user
+func GetAPITokensByUser(userID int) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// GetAPITokenByHash retrieves a token by the hash of its plaintext
+func GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := DB.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// TouchAPIToken records that a token was just used
+func TouchAPIToken(tokenID int) error {
+	return DB.Model(&models.APIToken{}).Where("id = ?", tokenID).UpdateColumn("last_used_at", time.Now()).Error
+}
+
+// DeleteAPIToken revokes a token belonging to the given user
+func DeleteAPIToken(tokenID, userID int) error {
+	result := DB.Where("id = ? AND user_id = ?", tokenID, userID).Delete(&models.APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
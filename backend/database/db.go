@@ -1,6 +1,11 @@
 package database
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
 	"pastebin/models"
 
 	"gorm.io/driver/sqlite"
@@ -9,16 +14,36 @@ import (
 
 var DB *gorm.DB
 
+var (
+	// ErrPasteExpired is returned when a paste exists but has passed its expiration time
+	ErrPasteExpired = errors.New("paste has expired")
+	// ErrPasteBurned is returned when a paste has reached its maximum number of views
+	ErrPasteBurned = errors.New("paste has been burned")
+	// ErrNotPasteOwner is returned when a user tries to modify someone else's paste
+	ErrNotPasteOwner = errors.New("paste belongs to another user")
+	// ErrSlugTaken is returned when a custom slug is already used by another paste
+	ErrSlugTaken = errors.New("slug is already taken")
+)
+
+// maxRandomIDAttempts bounds how often a colliding random ID is regenerated
+const maxRandomIDAttempts = 10
+
 // InitDB initializes the database connection and creates tables
 func InitDB() error {
 	var err error
-	DB, err = gorm.Open(sqlite.Open("./data/pastebin.db"), &gorm.Config{})
+	DB, err = gorm.Open(sqlite.Open("./data/pastebin.db"), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return err
 	}
 
 	// Auto migrate the schema
-	err = DB.AutoMigrate(&models.Paste{}, &models.Config{})
+	err = DB.AutoMigrate(&models.Paste{}, &models.PasteRevision{}, &models.User{}, &models.APIToken{}, &models.Session{}, &models.Config{}, &models.Tag{}, &models.Job{}, &models.AIUsage{}, &models.SecretFinding{}, &models.PasteExplanation{})
+	if err != nil {
+		return err
+	}
+
+	// Set up the full-text search index
+	err = initSearchIndex()
 	if err != nil {
 		return err
 	}
@@ -42,32 +67,67 @@ func CloseDB() {
 	}
 }
 
-// CreatePaste inserts a new paste into the database
+// CreatePaste inserts a new paste into the database. A custom slug is used as
+// its short link when given, otherwise a random ID is generated.
 func CreatePaste(paste *models.Paste) error {
-	// 生成随机ID，确保唯一性
-	for {
-		randomID, err := models.GenerateRandomID()
+	if paste.Slug != "" {
+		paste.RandomID = paste.Slug
+		err := insertPaste(paste)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrSlugTaken
+		}
+		return err
+	}
+
+	length, alphabet := pasteIDSettings()
+	for attempt := 0; attempt < maxRandomIDAttempts; attempt++ {
+		randomID, err := models.GenerateRandomID(length, alphabet)
 		if err != nil {
 			return err
 		}
+		if models.IsReservedID(randomID) {
+			continue
+		}
 
-		// 检查ID是否已存在
-		var count int64
-		err = DB.Model(&models.Paste{}).Where("random_id = ?", randomID).Count(&count).Error
+		// 依赖 random_id 的唯一索引保证唯一性，冲突时重新生成
+		paste.RandomID = randomID
+		err = insertPaste(paste)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to generate a unique paste ID after %d attempts", maxRandomIDAttempts)
+}
+
+// insertPaste inserts a paste together with its tags
+func insertPaste(paste *models.Paste) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := resolveTags(tx, paste.TagNames())
 		if err != nil {
 			return err
 		}
+		paste.Tags = tags
+		// The tags exist already, only the links to them are inserted
+		return tx.Omit("Tags.*").Create(paste).Error
+	})
+}
 
-		if count == 0 {
-			paste.RandomID = randomID
-			break
+// pasteIDSettings returns the configured short ID length and alphabet, falling back to the defaults
+func pasteIDSettings() (int, string) {
+	length := models.DefaultIDLength
+	if config, err := GetConfigByKey("paste_id_length"); err == nil {
+		if n, err := strconv.Atoi(config.Value); err == nil && n >= models.MinIDLength && n <= models.MaxIDLength {
+			length = n
 		}
-		// 如果ID已存在，重新生成
 	}
 
-	// 使用GORM创建记录
-	result := DB.Create(paste)
-	return result.Error
+	alphabet := models.DefaultIDAlphabet
+	if config, err := GetConfigByKey("paste_id_alphabet"); err == nil && models.IsValidIDAlphabet(config.Value) {
+		alphabet = config.Value
+	}
+
+	return length, alphabet
 }
 
 // GetPasteByID retrieves a paste by its ID (保留用于内部使用)
@@ -81,40 +141,112 @@ func GetPasteByID(id string) (*models.Paste, error) {
 }
 
 // GetPasteByRandomID retrieves a paste by its random ID
+// Expired pastes that have not been reaped yet are reported as ErrPasteExpired
 func GetPasteByRandomID(randomID string) (*models.Paste, error) {
 	var paste models.Paste
-	err := DB.Where("random_id = ?", randomID).First(&paste).Error
+	err := DB.Scopes(withTags).Where("random_id = ?", randomID).First(&paste).Error
+	if err != nil {
+		return nil, err
+	}
+	if paste.IsExpired() {
+		return nil, ErrPasteExpired
+	}
+	if paste.IsBurned() {
+		return nil, ErrPasteBurned
+	}
+	return &paste, nil
+}
+
+// ViewPasteByRandomID retrieves a paste for reading and counts the view.
+// The counter is bumped with a conditional update inside a transaction so that
+// concurrent readers can never exceed max_views; the reader that consumes the
+// last view gets the content and the stored content is wiped afterwards.
+func ViewPasteByRandomID(randomID string) (*models.Paste, error) {
+	var paste models.Paste
+	consumed := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Paste{}).
+			Where("random_id = ? AND (max_views = 0 OR view_count < max_views)", randomID).
+			Scopes(notExpired).
+			UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		consumed = true
+
+		if err := tx.Scopes(withTags).Where("random_id = ?", randomID).First(&paste).Error; err != nil {
+			return err
+		}
+
+		if paste.IsBurned() {
+			return tx.Model(&models.Paste{}).Where("id = ?", paste.ID).UpdateColumn("content", "").Error
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+
+	if !consumed {
+		// Nothing was updated, find out why
+		if _, err := GetPasteByRandomID(randomID); err != nil {
+			return nil, err
+		}
+		return nil, ErrPasteBurned
+	}
 	return &paste, nil
 }
 
-// GetAllPastes retrieves all pastes (limited to 100)
-func GetAllPastes() ([]models.Paste, error) {
+// GetPasteForks retrieves the live pastes that were forked from the given paste
+func GetPasteForks(pasteID int) ([]models.Paste, error) {
+	var forks []models.Paste
+	err := DB.Scopes(notExpired, withTags).Where("parent_id = ?", pasteID).Order("created_at DESC").Find(&forks).Error
+	if err != nil {
+		return nil, err
+	}
+	return forks, nil
+}
+
+// notExpired scopes a query to pastes that have not expired yet
+func notExpired(db *gorm.DB) *gorm.DB {
+	return db.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
+
+// ownedBy scopes a query to pastes owned by the given user
+func ownedBy(ownerID int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("owner_id = ?", ownerID)
+	}
+}
+
+// GetAllPastes retrieves all pastes of a user (limited to 100)
+func GetAllPastes(ownerID int) ([]models.Paste, error) {
 	var pastes []models.Paste
-	err := DB.Order("created_at DESC").Limit(100).Find(&pastes).Error
+	err := DB.Scopes(notExpired, ownedBy(ownerID), withTags).Order("created_at DESC").Limit(100).Find(&pastes).Error
 	if err != nil {
 		return nil, err
 	}
 	return pastes, nil
 }
 
-// GetPastesWithPagination retrieves pastes with pagination
-func GetPastesWithPagination(page, pageSize int) ([]models.Paste, int, error) {
+// GetPastesWithPagination retrieves pastes of a user with pagination, optionally filtered by tags
+func GetPastesWithPagination(ownerID int, tags models.TagFilter, page, pageSize int) ([]models.Paste, int, error) {
 	// Calculate offset
 	offset := (page - 1) * pageSize
 
 	// Get total count
 	var totalCount int64
-	err := DB.Model(&models.Paste{}).Count(&totalCount).Error
+	err := DB.Model(&models.Paste{}).Scopes(notExpired, ownedBy(ownerID), taggedWith(tags)).Count(&totalCount).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated results
 	var pastes []models.Paste
-	err = DB.Order("created_at DESC").Limit(pageSize).Offset(offset).Find(&pastes).Error
+	err = DB.Scopes(notExpired, ownedBy(ownerID), taggedWith(tags), withTags).Order("created_at DESC").Limit(pageSize).Offset(offset).Find(&pastes).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -122,31 +254,120 @@ func GetPastesWithPagination(page, pageSize int) ([]models.Paste, int, error) {
 	return pastes, int(totalCount), nil
 }
 
-// DeletePasteByRandomID deletes a paste by its random ID
-func DeletePasteByRandomID(randomID string) error {
-	result := DB.Where("random_id = ?", randomID).Delete(&models.Paste{})
-	if result.Error != nil {
-		return result.Error
+// GetPublicPastesWithPagination retrieves publicly listed pastes with pagination.
+// Pastes with a view limit are never listed since listing would bypass the limit.
+func GetPublicPastesWithPagination(page, pageSize int) ([]models.Paste, int, error) {
+	offset := (page - 1) * pageSize
+	query := DB.Model(&models.Paste{}).Scopes(notExpired).Where("visibility = ? AND max_views = 0", models.VisibilityPublic)
+
+	var totalCount int64
+	err := query.Count(&totalCount).Error
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if result.RowsAffected == 0 {
+	var pastes []models.Paste
+	err = query.Scopes(withTags).Order("created_at DESC").Limit(pageSize).Offset(offset).Find(&pastes).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return pastes, int(totalCount), nil
+}
+
+// DeletePasteByRandomID deletes a paste by its random ID if it belongs to the given user
+func DeletePasteByRandomID(randomID string, ownerID int) error {
+	var paste models.Paste
+	err := DB.Where("random_id = ?", randomID).First(&paste).Error
+	if err != nil {
+		return err
+	}
+	if !paste.IsOwnedBy(ownerID) {
+		return ErrNotPasteOwner
+	}
+
+	deleted, err := deletePastesWhere("id = ?", paste.ID)
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
 		return gorm.ErrRecordNotFound
 	}
 
 	return nil
 }
 
+// DeleteExpiredPastes hard-deletes all pastes whose expiration time has passed
+// and returns the number of rows removed
+func DeleteExpiredPastes() (int64, error) {
+	return deletePastesWhere("expires_at IS NOT NULL AND expires_at <= ?", time.Now())
+}
+
+// deletePastesWhere deletes the matching pastes together with the rows that belong to them
+func deletePastesWhere(query interface{}, args ...interface{}) (int64, error) {
+	var deleted int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		pasteIDs := tx.Model(&models.Paste{}).Select("id").Where(query, args...)
+
+		err := tx.Where("paste_id IN (?)", pasteIDs).Delete(&models.PasteRevision{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("paste_id IN (?)", pasteIDs).Delete(&models.Job{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("paste_id IN (?)", pasteIDs).Delete(&models.PasteExplanation{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("DELETE FROM paste_tags WHERE paste_id IN (?)", pasteIDs).Error
+		if err != nil {
+			return err
+		}
+
+		// Secret findings are kept for review, they just lose the link
+		err = tx.Model(&models.SecretFinding{}).Where("paste_id IN (?)", pasteIDs).Update("paste_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		// Forks outlive their parent, they just lose the link
+		err = tx.Model(&models.Paste{}).Where("parent_id IN (?)", pasteIDs).Update("parent_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where(query, args...).Delete(&models.Paste{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return pruneUnusedTags(tx)
+	})
+	return deleted, err
+}
+
 // initDefaultConfigs inserts default configuration values
 func initDefaultConfigs() error {
 	defaultConfigs := []models.Config{
 		// AI Configuration
 		{Key: "ai_enabled", Value: "false", Description: "Enable AI auto-generation of titles", Category: "ai"},
+		{Key: "ai_provider", Value: "openai", Description: "AI provider (openai, anthropic or ollama)", Category: "ai"},
 		{Key: "ai_base_url", Value: "https://api.openai.com/v1", Description: "AI API base URL", Category: "ai"},
 		{Key: "ai_api_key", Value: "", Description: "AI API key", Category: "ai"},
 		{Key: "ai_model", Value: "gpt-3.5-turbo", Description: "AI model to use", Category: "ai"},
 		{Key: "ai_prompt", Value: "Based on the following code/text content, generate a concise and descriptive title in Chinese (less than 50 characters):\n\n{content}", Description: "AI prompt template for title generation", Category: "ai"},
 		{Key: "ai_max_tokens", Value: "50", Description: "Maximum tokens for AI response", Category: "ai"},
 		{Key: "ai_temperature", Value: "0.7", Description: "AI temperature (creativity level)", Category: "ai"},
+		{Key: "ai_workers", Value: "2", Description: "Number of AI jobs processed concurrently (applied on restart)", Category: "ai"},
+		{Key: "ai_max_attempts", Value: "5", Description: "Attempts per AI job before it is given up", Category: "ai"},
+		{Key: "ai_daily_token_budget", Value: "0", Description: "Tokens the AI may use per day (UTC) before AI jobs pause, 0 for unlimited", Category: "ai"},
+		{Key: "ai_daily_request_budget", Value: "0", Description: "AI requests allowed per day (UTC) before AI jobs pause, 0 for unlimited", Category: "ai"},
 
 		// OAuth2 Configuration
 		{Key: "oauth2_enabled", Value: "false", Description: "Enable OAuth2 login", Category: "oauth2"},
@@ -158,6 +379,13 @@ func initDefaultConfigs() error {
 		{Key: "oauth2_redirect_url", Value: "http://localhost:8080/api/oauth2/callback", Description: "OAuth2 Redirect URL", Category: "oauth2"},
 		{Key: "oauth2_scopes", Value: "read:user", Description: "OAuth2 Scopes", Category: "oauth2"},
 		{Key: "oauth2_name", Value: "", Description: "OAuth2 Name", Category: "oauth2"},
+
+		// Paste Configuration
+		{Key: "paste_default_visibility", Value: "unlisted", Description: "Default visibility of new pastes (public, unlisted or private)", Category: "paste"},
+		{Key: "paste_id_length", Value: strconv.Itoa(models.DefaultIDLength), Description: "Length of generated short IDs (4-32)", Category: "paste"},
+		{Key: "paste_highlight_theme", Value: "github", Description: "Default syntax highlighting theme for rendered pastes", Category: "paste"},
+		{Key: "paste_id_alphabet", Value: models.DefaultIDAlphabet, Description: "Characters used in generated short IDs (letters, digits, '-' and '_')", Category: "paste"},
+		{Key: "paste_secret_policy", Value: models.SecretPolicyPrivate, Description: "What to do with pastes containing secrets (off, block, private or redact)", Category: "paste"},
 	}
 
 	for _, config := range defaultConfigs {
@@ -218,6 +446,8 @@ func UpdateConfig(key, value string) error {
 			switch key {
 			case "ai_enabled":
 				description = "Enable AI auto-title generation"
+			case "ai_provider":
+				description = "AI provider"
 			case "ai_base_url":
 				description = "AI API base URL"
 			case "ai_api_key":
@@ -233,6 +463,14 @@ func UpdateConfig(key, value string) error {
 			default:
 				description = "AI configuration"
 			}
+		} else if len(key) >= 6 && key[:6] == "paste_" {
+			category = "paste"
+			switch key {
+			case "paste_default_visibility":
+				description = "Default visibility of new pastes"
+			default:
+				description = "Paste configuration"
+			}
 		} else if len(key) >= 7 && key[:7] == "oauth2_" {
 			category = "oauth2"
 			switch key {
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"os"
+	"testing"
+)
+
+// setupTestDB creates an empty database in a temporary directory for the test
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("data", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(CloseDB)
+}
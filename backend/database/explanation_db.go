@@ -0,0 +1,51 @@
+package database
+
+import (
+	"errors"
+
+	"pastebin/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// Paste explanation related database functions
+
+// GetPasteExplanation retrieves the cached explanation of a paste revision, nil when there is none
+func GetPasteExplanation(pasteID, revision int) (*models.PasteExplanation, error) {
+	// Find instead of First, a missing explanation is the normal case and not worth logging
+	var explanation models.PasteExplanation
+	result := DB.Where("paste_id = ? AND revision = ?", pasteID, revision).Limit(1).Find(&explanation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &explanation, nil
+}
+
+// SavePasteExplanation caches the explanation of a paste revision and returns the cached
+// one. When another request cached the same revision first, that explanation is kept and
+// returned instead.
+func SavePasteExplanation(explanation *models.PasteExplanation) (*models.PasteExplanation, error) {
+	result := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "paste_id"}, {Name: "revision"}},
+		DoNothing: true,
+	}).Create(explanation)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return explanation, nil
+	}
+
+	stored, err := GetPasteExplanation(explanation.PasteID, explanation.Revision)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, errors.New("explanation was neither saved nor found")
+	}
+	stored.Cached = true
+	return stored, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+
+	"pastebin/models"
+)
+
+func TestSavePasteExplanation(t *testing.T) {
+	setupTestDB(t)
+
+	paste := models.Paste{Content: "print(1)", Visibility: models.VisibilityPublic, Revision: 1}
+	if err := CreatePaste(&paste); err != nil {
+		t.Fatal(err)
+	}
+
+	missing, err := GetPasteExplanation(paste.ID, 1)
+	if err != nil || missing != nil {
+		t.Fatalf("GetPasteExplanation() = %v, %v before saving, want nil, nil", missing, err)
+	}
+
+	first := &models.PasteExplanation{PasteID: paste.ID, Revision: 1, Summary: "first"}
+	saved, err := SavePasteExplanation(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Summary != "first" || saved.Cached {
+		t.Errorf("first save returned %q cached=%v, want the new explanation", saved.Summary, saved.Cached)
+	}
+
+	// A concurrent request finishing second gets the explanation stored first
+	second := &models.PasteExplanation{PasteID: paste.ID, Revision: 1, Summary: "second"}
+	saved, err = SavePasteExplanation(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Summary != "first" || !saved.Cached {
+		t.Errorf("conflicting save returned %q cached=%v, want the stored explanation", saved.Summary, saved.Cached)
+	}
+
+	// Every revision has its own explanation
+	other := &models.PasteExplanation{PasteID: paste.ID, Revision: 2, Summary: "revision 2"}
+	if saved, err = SavePasteExplanation(other); err != nil || saved.Summary != "revision 2" {
+		t.Errorf("saving another revision returned %v, %v", saved, err)
+	}
+
+	stored, err := GetPasteExplanation(paste.ID, 1)
+	if err != nil || stored == nil || stored.Summary != "first" {
+		t.Errorf("GetPasteExplanation() = %v, %v, want the first explanation", stored, err)
+	}
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"time"
+
+	"pastebin/models"
+
+	"gorm.io/gorm"
+)
+
+// Background job related database functions.
+//
+// A worker claims a job by leasing it: the job is moved to running with a lease
+// expiration time. A job whose lease expired, because the server stopped while it
+// was running, becomes claimable again, so no job is lost across restarts.
+
+// claimable scopes a query to jobs a worker may start now
+func claimable(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((state IN ? AND run_at <= ?) OR (state = ? AND leased_until < ?))",
+			[]string{models.JobQueued, models.JobFailed}, now, models.JobRunning, now).
+			Where("attempts < max_attempts")
+	}
+}
+
+// EnqueueJob inserts a job that may run immediately
+func EnqueueJob(job *models.Job) error {
+	job.State = models.JobQueued
+	job.Attempts = 0
+	job.RunAt = time.Now()
+	return DB.Create(job).Error
+}
+
+// ClaimJob leases the next claimable job for the given duration and counts the attempt.
+// It returns nil when there is nothing to do.
+func ClaimJob(lease time.Duration) (*models.Job, error) {
+	var claimed *models.Job
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Find instead of First, an empty queue is the normal case and not worth logging
+		var job models.Job
+		result := tx.Scopes(claimable(now)).Order("run_at ASC, id ASC").Limit(1).Find(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		leasedUntil := now.Add(lease)
+		result = tx.Model(&models.Job{}).Where("id = ?", job.ID).Scopes(claimable(now)).Updates(map[string]interface{}{
+			"state":        models.JobRunning,
+			"leased_until": leasedUntil,
+			"attempts":     gorm.Expr("attempts + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Somebody else claimed it first
+			return nil
+		}
+
+		job.State = models.JobRunning
+		job.LeasedUntil = &leasedUntil
+		job.Attempts++
+		claimed = &job
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// CompleteJob marks a job as succeeded
+func CompleteJob(jobID int) error {
+	now := time.Now()
+	return DB.Model(&models.Job{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+		"state":        models.JobSucceeded,
+		"leased_until": nil,
+		"last_error":   "",
+		"finished_at":  now,
+	}).Error
+}
+
+// FailJob records a failed attempt. The job is retried at retryAt, or marked dead
+// when retryAt is nil.
+func FailJob(jobID int, cause error, retryAt *time.Time) error {
+	updates := map[string]interface{}{
+		"leased_until": nil,
+		"last_error":   cause.Error(),
+	}
+	if retryAt != nil {
+		updates["state"] = models.JobFailed
+		updates["run_at"] = *retryAt
+	} else {
+		updates["state"] = models.JobDead
+		updates["finished_at"] = time.Now()
+	}
+	return DB.Model(&models.Job{}).Where("id = ?", jobID).Updates(updates).Error
+}
+
+// RequeueJob puts a running job back in the queue without counting its attempt,
+// for jobs that could not run at all, e.g. because AI was switched off meanwhile
+func RequeueJob(jobID int) error {
+	return DB.Model(&models.Job{}).Where("id = ? AND state = ?", jobID, models.JobRunning).Updates(map[string]interface{}{
+		"state":        models.JobQueued,
+		"leased_until": nil,
+		"run_at":       time.Now(),
+		"attempts":     gorm.Expr("CASE WHEN attempts > 0 THEN attempts - 1 ELSE 0 END"),
+	}).Error
+}
+
+// BuryAbandonedJobs marks jobs as dead whose lease expired on their last allowed attempt
+func BuryAbandonedJobs() (int64, error) {
+	now := time.Now()
+	result := DB.Model(&models.Job{}).
+		Where("state = ? AND leased_until < ? AND attempts >= max_attempts", models.JobRunning, now).
+		Updates(map[string]interface{}{
+			"state":        models.JobDead,
+			"leased_until": nil,
+			"last_error":   "lease expired",
+			"finished_at":  now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// needsClassification scopes a query to pastes the AI may classify but has not yet.
+// Password protected pastes are skipped so their content cannot leak through a generated title,
+// encrypted pastes are skipped since the server only holds their ciphertext, and pastes
+// with secrets the policy kept in them are skipped so the secrets never reach the AI provider.
+func needsClassification(db *gorm.DB) *gorm.DB {
+	return db.Where("ai_classified = ?", false).
+		Where("password_hash IS NULL OR password_hash = ''").
+		Where("encrypted = ?", false).
+		Where("NOT "+keptSecretsCondition, models.SecretPolicyRedact)
+}
+
+// EnqueueUnclassifiedPastes queues a classification job for every unclassified paste
+// without an unfinished one, such as pastes created before the job queue existed or
+// pastes skipped while they had a password
+func EnqueueUnclassifiedPastes(maxAttempts int) (int64, error) {
+	return enqueueClassification(DB.Model(&models.Paste{}), maxAttempts)
+}
+
+// EnqueuePasteIfUnclassified queues a classification job for the paste when it still
+// needs one and has no unfinished job, e.g. after its password was removed
+func EnqueuePasteIfUnclassified(pasteID, maxAttempts int) (bool, error) {
+	queued, err := enqueueClassification(DB.Model(&models.Paste{}).Where("id = ?", pasteID), maxAttempts)
+	return queued > 0, err
+}
+
+// enqueueClassification queues a classification job for the given pastes that need one. A
+// succeeded job does not count, it skips a paste that got a password before the job ran.
+func enqueueClassification(pastes *gorm.DB, maxAttempts int) (int64, error) {
+	result := DB.Exec(`INSERT INTO jobs (type, paste_id, state, attempts, max_attempts, run_at, last_error, created_at, updated_at)
+		SELECT ?, pastes.id, ?, 0, ?, ?, '', ?, ? FROM pastes WHERE id IN (?)`,
+		models.JobTypeClassifyPaste, models.JobQueued, maxAttempts, time.Now(), time.Now(), time.Now(),
+		pastes.Select("id").Scopes(needsClassification).
+			Where("NOT EXISTS (SELECT 1 FROM jobs WHERE jobs.paste_id = pastes.id AND jobs.type = ? AND jobs.state <> ?)",
+				models.JobTypeClassifyPaste, models.JobSucceeded))
+	return result.RowsAffected, result.Error
+}
+
+// GetJobCounts returns the number of jobs in each state
+func GetJobCounts() (map[string]int64, error) {
+	var rows []struct {
+		State string
+		Count int64
+	}
+	err := DB.Model(&models.Job{}).Select("state, COUNT(*) AS count").Group("state").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int64{
+		models.JobQueued:    0,
+		models.JobRunning:   0,
+		models.JobSucceeded: 0,
+		models.JobFailed:    0,
+		models.JobDead:      0,
+	}
+	for _, row := range rows {
+		counts[row.State] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"pastebin/models"
+
+	"gorm.io/gorm"
+)
+
+// Paste revision related database functions
+
+// ErrPasteViewLimited is returned when trying to edit a paste with a view limit or to read
+// it outside the view counting endpoints
+var ErrPasteViewLimited = errors.New("pastes with a view limit cannot be edited or read by revision")
+
+// ErrEditConflict is returned when somebody else edited the paste while it was being edited
+var ErrEditConflict = errors.New("paste was edited concurrently")
+
+// UpdatePasteByRandomID edits a paste owned by the given user in place, keeping its random ID.
+// Changing the title or content stores the version being replaced in the revisions table first,
+// changing only the visibility, password, language or tags does not create a new revision.
+// All changes are applied together or not at all.
+func UpdatePasteByRandomID(randomID string, ownerID int, req models.UpdatePasteRequest) (*models.Paste, error) {
+	paste, err := GetPasteByRandomID(randomID)
+	if err != nil {
+		return nil, err
+	}
+	if !paste.IsOwnedBy(ownerID) {
+		return nil, ErrNotPasteOwner
+	}
+
+	newRevision := req.Title != nil || req.Content != nil
+	if newRevision && paste.MaxViews > 0 {
+		return nil, ErrPasteViewLimited
+	}
+
+	updates := map[string]interface{}{}
+	if req.Visibility != nil {
+		paste.Visibility = *req.Visibility
+		updates["visibility"] = paste.Visibility
+	}
+	if req.Password != nil {
+		if err := paste.SetPassword(*req.Password); err != nil {
+			return nil, err
+		}
+		updates["password_hash"] = paste.PasswordHash
+
+		// The AI description summarizes the content, it must not outlive the new password.
+		// Classification is redone once the password is removed again.
+		if paste.PasswordHash != "" {
+			paste.Description = ""
+			paste.AIClassified = false
+			updates["description"] = ""
+			updates["ai_classified"] = false
+		}
+	}
+	if req.Language != nil {
+		paste.Language = *req.Language
+		updates["language"] = paste.Language
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if newRevision {
+			// The revision being replaced was already stored by a concurrent edit
+			err := tx.Create(paste.CurrentRevision()).Error
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrEditConflict
+			}
+			if err != nil {
+				return err
+			}
+
+			if req.Title != nil {
+				paste.Title = *req.Title
+			}
+			if req.Content != nil && *req.Content != paste.Content {
+				paste.Content = *req.Content
+
+				// The AI description summarized the old content, the paste is classified again
+				paste.Description = ""
+				paste.AIClassified = false
+				updates["description"] = ""
+				updates["ai_classified"] = false
+			}
+			editedAt := time.Now()
+			paste.EditedAt = &editedAt
+			paste.Revision++
+
+			updates["title"] = paste.Title
+			updates["content"] = paste.Content
+			updates["edited_at"] = paste.EditedAt
+			updates["revision"] = paste.Revision
+		}
+
+		if len(updates) > 0 {
+			// Only bump the revision if nobody else edited the paste in the meantime
+			query := tx.Model(&models.Paste{}).Where("id = ?", paste.ID)
+			if newRevision {
+				query = query.Where("revision = ?", paste.Revision-1)
+			}
+			result := query.Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if newRevision && result.RowsAffected == 0 {
+				return ErrEditConflict
+			}
+		}
+
+		if req.Tags != nil {
+			return replacePasteTags(tx, paste, *req.Tags)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paste, nil
+}
+
+// GetPasteRevisions retrieves every version of a paste, oldest first, including the current one.
+// Pastes with a view limit only expose their content through the view counting endpoints.
+func GetPasteRevisions(randomID string) ([]models.PasteRevision, error) {
+	paste, err := GetPasteByRandomID(randomID)
+	if err != nil {
+		return nil, err
+	}
+	if paste.MaxViews > 0 {
+		return nil, ErrPasteViewLimited
+	}
+
+	var revisions []models.PasteRevision
+	err = DB.Where("paste_id = ?", paste.ID).Order("revision ASC").Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return append(revisions, *paste.CurrentRevision()), nil
+}
+
+// GetPasteRevision retrieves a single version of a paste by revision number,
+// revision 0 means the current version.
+// Pastes with a view limit only expose their content through the view counting endpoints.
+func GetPasteRevision(randomID string, revision int) (*models.PasteRevision, error) {
+	paste, err := GetPasteByRandomID(randomID)
+	if err != nil {
+		return nil, err
+	}
+	if paste.MaxViews > 0 {
+		return nil, ErrPasteViewLimited
+	}
+
+	if revision == 0 || revision == paste.Revision {
+		return paste.CurrentRevision(), nil
+	}
+
+	var rev models.PasteRevision
+	err = DB.Where("paste_id = ? AND revision = ?", paste.ID, revision).First(&rev).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"errors"
+	"html"
+	"log"
+	"strings"
+	"unicode"
+
+	"pastebin/models"
+
+	"gorm.io/gorm"
+)
+
+// Full-text search related database functions.
+//
+// Pastes are indexed in the pastes_fts FTS5 table, keyed by the paste ID and kept
+// in sync by triggers so every write path (create, edit, burn, cleanup) is covered.
+// The ciphertext of encrypted pastes is never indexed, only their title.
+
+var (
+	// ErrSearchUnavailable is returned when SQLite was built without FTS5
+	ErrSearchUnavailable = errors.New("full-text search is not available, build with -tags sqlite_fts5")
+	// ErrInvalidSearchQuery is returned when a query contains nothing searchable
+	ErrInvalidSearchQuery = errors.New("search query has no searchable terms")
+)
+
+const (
+	// Relevance weights of the title and content columns for bm25
+	searchTitleWeight   = 10.0
+	searchContentWeight = 1.0
+	// searchSnippetTokens is the number of tokens around a match shown in a snippet
+	searchSnippetTokens = 24
+)
+
+// Private use characters mark matches in FTS output until the text has been escaped
+const (
+	matchStart = "\uE000"
+	matchEnd   = "\uE001"
+)
+
+// searchAvailable records whether the FTS5 index could be set up
+var searchAvailable bool
+
+// indexedContent is the SQL for the content stored in the index, empty for encrypted pastes
+func indexedContent(row string) string {
+	return "CASE WHEN " + row + ".encrypted THEN '' ELSE " + row + ".content END"
+}
+
+var searchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS pastes_fts USING fts5(title, content, tokenize = 'unicode61 remove_diacritics 2')`,
+	`CREATE TRIGGER IF NOT EXISTS pastes_fts_insert AFTER INSERT ON pastes BEGIN
+		INSERT INTO pastes_fts (rowid, title, content) VALUES (new.id, new.title, ` + indexedContent("new") + `);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS pastes_fts_update AFTER UPDATE OF title, content, encrypted ON pastes BEGIN
+		DELETE FROM pastes_fts WHERE rowid = old.id;
+		INSERT INTO pastes_fts (rowid, title, content) VALUES (new.id, new.title, ` + indexedContent("new") + `);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS pastes_fts_delete AFTER DELETE ON pastes BEGIN
+		DELETE FROM pastes_fts WHERE rowid = old.id;
+	END`,
+}
+
+// searchTriggers are the triggers keeping the index in sync
+var searchTriggers = []string{"pastes_fts_insert", "pastes_fts_update", "pastes_fts_delete"}
+
+// initSearchIndex creates the FTS5 index and its triggers. Without FTS5 support the
+// server still starts with search disabled, and the triggers are dropped so writes
+// keep working. The index is rebuilt whenever its triggers were missing, as pastes
+// written in the meantime were not indexed.
+func initSearchIndex() error {
+	var fts5 bool
+	if err := DB.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	if !fts5 {
+		for _, trigger := range searchTriggers {
+			if err := DB.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("Full-text search disabled: %v", ErrSearchUnavailable)
+		return nil
+	}
+
+	var triggers int64
+	err := DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ?", searchTriggers).Scan(&triggers).Error
+	if err != nil {
+		return err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range searchSchema {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if int(triggers) == len(searchTriggers) {
+			return nil
+		}
+		if err := tx.Exec("DELETE FROM pastes_fts").Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO pastes_fts (rowid, title, content) SELECT id, title, " +
+			indexedContent("pastes") + " FROM pastes").Error
+	})
+	if err != nil {
+		return err
+	}
+
+	searchAvailable = true
+	return nil
+}
+
+// SearchPastes runs a full-text search over a user's pastes, best matches first.
+// Titles weigh more than content, and matches are highlighted with <mark> in the
+// returned title and snippet, which are otherwise HTML escaped.
+func SearchPastes(ownerID int, query string, page, pageSize int) ([]models.PasteSearchResult, int, error) {
+	if !searchAvailable {
+		return nil, 0, ErrSearchUnavailable
+	}
+	match := buildMatchQuery(query)
+	if match == "" {
+		return nil, 0, ErrInvalidSearchQuery
+	}
+
+	search := func() *gorm.DB {
+		return DB.Table("pastes_fts").
+			Joins("JOIN pastes ON pastes.id = pastes_fts.rowid").
+			Where("pastes_fts MATCH ?", match).
+			Scopes(notExpired, ownedBy(ownerID))
+	}
+
+	var totalCount int64
+	if err := search().Count(&totalCount).Error; err != nil {
+		return nil, 0, err
+	}
+
+	results := []models.PasteSearchResult{}
+	err := search().
+		Select("pastes.*, "+
+			"highlight(pastes_fts, 0, ?, ?) AS title_highlight, "+
+			"snippet(pastes_fts, 1, ?, ?, '…', ?) AS snippet, "+
+			"bm25(pastes_fts, ?, ?) AS score",
+			matchStart, matchEnd, matchStart, matchEnd, searchSnippetTokens, searchTitleWeight, searchContentWeight).
+		Order("score").
+		Limit(pageSize).
+		Offset((page - 1) * pageSize).
+		Scan(&results).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pastes := make([]*models.Paste, 0, len(results))
+	for i := range results {
+		results[i].PasswordProtected = results[i].PasswordHash != ""
+		results[i].TitleHighlight = markMatches(results[i].TitleHighlight)
+		results[i].Snippet = markMatches(results[i].Snippet)
+		pastes = append(pastes, &results[i].Paste)
+	}
+	if err := loadTags(pastes); err != nil {
+		return nil, 0, err
+	}
+
+	return results, int(totalCount), nil
+}
+
+// buildMatchQuery turns user input into an FTS5 query. Quoted text is matched as a
+// phrase, a trailing * makes a prefix query, and all terms must match. Everything
+// is passed as quoted strings so user input can never be read as FTS5 syntax.
+func buildMatchQuery(input string) string {
+	var terms []string
+	addTerm := func(text string, prefix bool) {
+		words := strings.FieldsFunc(text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		if len(words) == 0 {
+			return
+		}
+		term := `"` + strings.Join(words, " ") + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+
+	for input != "" {
+		input = strings.TrimLeftFunc(input, unicode.IsSpace)
+		if input == "" {
+			break
+		}
+
+		if input[0] == '"' {
+			phrase := input[1:]
+			end := strings.IndexByte(phrase, '"')
+			if end < 0 {
+				// An unterminated quote runs to the end of the query
+				end = len(phrase)
+			}
+			input = phrase[min(end+1, len(phrase)):]
+			prefix := strings.HasPrefix(input, "*")
+			if prefix {
+				input = input[1:]
+			}
+			addTerm(phrase[:end], prefix)
+			continue
+		}
+
+		end := strings.IndexFunc(input, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(input)
+		}
+		word := input[:end]
+		input = input[end:]
+		addTerm(strings.TrimSuffix(word, "*"), strings.HasSuffix(word, "*"))
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// markMatches escapes FTS output and turns the match markers into <mark> tags
+func markMatches(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, matchStart, "<mark>")
+	return strings.ReplaceAll(text, matchEnd, "</mark>")
+}
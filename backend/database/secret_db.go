@@ -0,0 +1,47 @@
+package database
+
+import (
+	"pastebin/models"
+)
+
+// Secret finding related database functions
+
+// keptSecretsCondition matches pastes with secrets that the policy left in their content,
+// it takes the redact policy as its argument. Findings outlive edits, a paste once holding
+// a secret is treated as holding it from then on.
+const keptSecretsCondition = "EXISTS (SELECT 1 FROM secret_findings WHERE secret_findings.paste_id = pastes.id AND secret_findings.action <> ?)"
+
+// PasteKeepsSecrets reports whether the paste holds secrets that were not redacted
+func PasteKeepsSecrets(pasteID int) (bool, error) {
+	var count int64
+	err := DB.Model(&models.Paste{}).Where("id = ?", pasteID).
+		Where(keptSecretsCondition, models.SecretPolicyRedact).Count(&count).Error
+	return count > 0, err
+}
+
+// RecordSecretFindings stores the secrets found in a paste
+func RecordSecretFindings(findings []models.SecretFinding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	return DB.Create(&findings).Error
+}
+
+// GetSecretFindings retrieves secret findings with pagination, most recent first
+func GetSecretFindings(page, pageSize int) ([]models.SecretFinding, int, error) {
+	offset := (page - 1) * pageSize
+
+	var totalCount int64
+	err := DB.Model(&models.SecretFinding{}).Count(&totalCount).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findings := []models.SecretFinding{}
+	err = DB.Order("created_at DESC, id DESC").Limit(pageSize).Offset(offset).Find(&findings).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return findings, int(totalCount), nil
+}
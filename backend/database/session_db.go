@@ -0,0 +1,64 @@
+package database
+
+import (
+	"time"
+
+	"pastebin/models"
+
+	"gorm.io/gorm"
+)
+
+// Session related database functions
+
+// CreateSession inserts a new session and purges the user's expired ones
+func CreateSession(session *models.Session) error {
+	err := DB.Where("user_id = ? AND expires_at <= ?", session.UserID, time.Now()).Delete(&models.Session{}).Error
+	if err != nil {
+		return err
+	}
+	return DB.Create(session).Error
+}
+
+// GetActiveSession retrieves a session that has not expired or been revoked
+func GetActiveSession(sessionID string) (*models.Session, error) {
+	var session models.Session
+	err := DB.Where("id = ? AND expires_at > ?", sessionID, time.Now()).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession records activity on a session
+func TouchSession(sessionID string) error {
+	return DB.Model(&models.Session{}).Where("id = ?", sessionID).UpdateColumn("last_seen_at", time.Now()).Error
+}
+
+// GetActiveSessionsByUser retrieves the active sessions of a user, most recent first
+func GetActiveSessionsByUser(userID int) ([]models.Session, error) {
+	var sessions []models.Session
+	err := DB.Where("user_id = ? AND expires_at > ?", userID, time.Now()).Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession deletes a session of the given user
+func RevokeSession(sessionID string, userID int) error {
+	result := DB.Where("id = ? AND user_id = ?", sessionID, userID).Delete(&models.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions deletes every session of a user except the one given (which may be empty)
+// and returns how many were revoked
+func RevokeUserSessions(userID int, exceptSessionID string) (int64, error) {
+	result := DB.Where("user_id = ? AND id <> ?", userID, exceptSessionID).Delete(&models.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"pastebin/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tag related database functions
+
+// withTags preloads the tags of the queried pastes in alphabetical order
+func withTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name ASC")
+	})
+}
+
+// taggedWith scopes a query to pastes carrying all or any of the filter's tags
+func taggedWith(filter models.TagFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(filter.Names) == 0 {
+			return db
+		}
+
+		tagged := DB.Table("paste_tags").
+			Select("paste_tags.paste_id").
+			Joins("JOIN tags ON tags.id = paste_tags.tag_id").
+			Where("tags.name IN ?", filter.Names)
+		if filter.MatchAll {
+			tagged = tagged.Group("paste_tags.paste_id").
+				Having("COUNT(DISTINCT tags.id) = ?", len(filter.Names))
+		}
+		return db.Where("pastes.id IN (?)", tagged)
+	}
+}
+
+// resolveTags returns the tags with the given names, creating the missing ones
+func resolveTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	// Concurrent writers may create the same tag, existing names are left alone
+	err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).
+		Create(models.TagsFromNames(names)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []models.Tag
+	if err := tx.Where("name IN ?", names).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// replacePasteTags replaces all tags of a paste
+func replacePasteTags(tx *gorm.DB, paste *models.Paste, names []string) error {
+	tags, err := resolveTags(tx, names)
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(paste).Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	paste.Tags = tags
+	return pruneUnusedTags(tx)
+}
+
+// pruneUnusedTags deletes tags no paste carries anymore
+func pruneUnusedTags(tx *gorm.DB) error {
+	return tx.Where("id NOT IN (?)", tx.Table("paste_tags").Select("tag_id")).Delete(&models.Tag{}).Error
+}
+
+// loadTags attaches their tags to pastes that were not loaded through a preload
+func loadTags(pastes []*models.Paste) error {
+	if len(pastes) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(pastes))
+	for _, paste := range pastes {
+		ids = append(ids, paste.ID)
+	}
+
+	var rows []struct {
+		PasteID int
+		models.Tag
+	}
+	err := DB.Table("tags").
+		Select("paste_tags.paste_id, tags.*").
+		Joins("JOIN paste_tags ON paste_tags.tag_id = tags.id").
+		Where("paste_tags.paste_id IN ?", ids).
+		Order("tags.name ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	tags := make(map[int][]models.Tag, len(pastes))
+	for _, row := range rows {
+		tags[row.PasteID] = append(tags[row.PasteID], row.Tag)
+	}
+	for _, paste := range pastes {
+		paste.Tags = tags[paste.ID]
+	}
+	return nil
+}
+
+// GetTagCounts returns the tags used by a user's live pastes with the number of pastes each,
+// most used first
+func GetTagCounts(ownerID int) ([]models.TagCount, error) {
+	counts := []models.TagCount{}
+	err := DB.Table("tags").
+		Select("tags.name, COUNT(*) AS count").
+		Joins("JOIN paste_tags ON paste_tags.tag_id = tags.id").
+		Joins("JOIN pastes ON pastes.id = paste_tags.paste_id").
+		Scopes(notExpired, ownedBy(ownerID)).
+		Group("tags.id").
+		Order("count DESC, tags.name ASC").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
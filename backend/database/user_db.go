@@ -0,0 +1,147 @@
+package database
+
+import (
+	"errors"
+
+	"pastebin/models"
+
+	"gorm.io/gorm"
+)
+
+// User related database functions
+
+// ErrUserExists is returned when creating a user whose username is already taken
+var ErrUserExists = errors.New("username already exists")
+
+// CreateUser inserts a new user
+func CreateUser(user *models.User) error {
+	var count int64
+	err := DB.Model(&models.User{}).Where("provider = ? AND username = ?", user.Provider, user.Username).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrUserExists
+	}
+
+	return DB.Create(user).Error
+}
+
+// GetUserByID retrieves a user by ID
+func GetUserByID(id int) (*models.User, error) {
+	var user models.User
+	err := DB.Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetLocalUserByUsername retrieves a local (password) user by username
+func GetLocalUserByUsername(username string) (*models.User, error) {
+	var user models.User
+	err := DB.Where("provider = ? AND username = ?", models.UserProviderLocal, username).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetAllUsers retrieves all users
+func GetAllUsers() ([]models.User, error) {
+	var users []models.User
+	err := DB.Order("id ASC").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetOrCreateOAuth2User returns the user linked to the OAuth2 account, creating it on first login
+func GetOrCreateOAuth2User(providerID, username string) (*models.User, error) {
+	var user models.User
+	err := DB.Where("provider = ? AND provider_id = ?", models.UserProviderOAuth2, providerID).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user = models.User{
+		Username:   username,
+		Provider:   models.UserProviderOAuth2,
+		ProviderID: providerID,
+		Role:       models.RoleMember,
+	}
+	err = CreateUser(&user)
+	if errors.Is(err, ErrUserExists) {
+		// Another OAuth2 account already uses this name, disambiguate with the provider ID
+		user.Username = username + "#" + providerID
+		err = CreateUser(&user)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUserPassword stores a new password hash for a user
+func UpdateUserPassword(userID int, passwordHash string) error {
+	return DB.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", passwordHash).Error
+}
+
+// UpdateUserRole changes the role of a user
+func UpdateUserRole(userID int, role string) error {
+	result := DB.Model(&models.User{}).Where("id = ?", userID).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// EnsureLocalUser creates the local user with the given credentials and role if it does not exist yet.
+// Pastes created before accounts existed are handed over to the first such user.
+func EnsureLocalUser(username, password, role string) (*models.User, error) {
+	user, err := GetLocalUserByUsername(username)
+	if err == nil {
+		// Accounts created before roles existed default to member, make sure someone can still administer
+		if role == models.RoleAdmin && user.Role != models.RoleAdmin {
+			var admins int64
+			if err := DB.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+				return nil, err
+			}
+			if admins == 0 {
+				if err := UpdateUserRole(user.ID, role); err != nil {
+					return nil, err
+				}
+				user.Role = role
+			}
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user = &models.User{
+		Username: username,
+		Provider: models.UserProviderLocal,
+		Role:     role,
+	}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	err = DB.Model(&models.Paste{}).Where("owner_id IS NULL").Update("owner_id", user.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -8,8 +8,11 @@ import (
 	"syscall"
 
 	"pastebin/database"
+	"pastebin/middleware"
+	"pastebin/models"
 	"pastebin/routes"
 	"pastebin/services"
+	"pastebin/token"
 )
 
 func main() {
@@ -20,6 +23,19 @@ func main() {
 	}
 	defer database.CloseDB()
 
+	// Load JWT signing keys
+	err = token.Init()
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
+	// Make sure the admin account from the environment exists
+	adminUsername, adminPassword := middleware.GetCredentials()
+	_, err = database.EnsureLocalUser(adminUsername, adminPassword, models.RoleAdmin)
+	if err != nil {
+		log.Fatal("Failed to create admin user:", err)
+	}
+
 	// Set up routes
 	router := routes.SetupRoutes()
 
@@ -27,6 +43,10 @@ func main() {
 	aiProcessor := services.NewAIProcessorService()
 	aiProcessor.Start()
 
+	// Start expired paste reaper
+	pasteReaper := services.NewPasteReaperService()
+	pasteReaper.Start()
+
 	// Set up graceful shutdown
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -34,6 +54,7 @@ func main() {
 		<-c
 		log.Println("Shutting down gracefully...")
 		aiProcessor.Stop()
+		pasteReaper.Stop()
 		database.CloseDB()
 		os.Exit(0)
 	}()
@@ -1,50 +1,232 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"pastebin/database"
+	"pastebin/models"
+	"pastebin/token"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("your-secret-key")
+// Context keys set by AuthMiddleware
+const (
+	ContextUserIDKey    = "user_id"
+	ContextUsernameKey  = "username"
+	ContextRoleKey      = "role"
+	ContextScopesKey    = "scopes"
+	ContextSessionIDKey = "session_id"
+)
+
+// sessionTouchInterval limits how often a session's last seen time is written
+const sessionTouchInterval = time.Minute
 
-// AuthMiddleware checks for valid JWT token
+// AuthMiddleware checks for a valid JWT cookie or a personal access token
+// sent as "Authorization: Bearer <token>"
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenString, err := c.Cookie("token")
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "No token provided"})
+		if message := authenticate(c); message != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
 			c.Abort()
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+// OptionalAuthMiddleware identifies the user when credentials are present
+// but lets anonymous requests through, for endpoints readable by everyone
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c)
+		c.Next()
+	}
+}
+
+// authenticate stores the authenticated user in the context and returns
+// an error message when the request carries no valid credentials
+func authenticate(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		if !authenticateAPIToken(c, strings.TrimPrefix(header, "Bearer ")) {
+			return "Invalid token"
+		}
+		return ""
+	}
+
+	tokenString, err := c.Cookie("token")
+	if err != nil {
+		return "No token provided"
+	}
+
+	claims, err := ParseSessionToken(tokenString)
+	if err != nil {
+		return "Invalid token"
+	}
+
+	c.Set(ContextSessionIDKey, claims.SessionID)
+	c.Set(ContextUserIDKey, claims.UserID)
+	c.Set(ContextUsernameKey, claims.Username)
+	c.Set(ContextRoleKey, claims.Role)
+	return ""
+}
+
+// ParseSessionToken validates a login token and checks that its session
+// has not been revoked by logout, a password change or an admin. The claims
+// carry the current name and role of the user, not the ones at login.
+func ParseSessionToken(tokenString string) (*token.UserClaims, error) {
+	claims, err := token.ParseUserToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := database.GetActiveSession(claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != claims.UserID {
+		return nil, errors.New("session belongs to another user")
+	}
+
+	// A role change applies to sessions started before it
+	user, err := database.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	claims.Username = user.Username
+	claims.Role = user.Role
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		if err := database.TouchSession(session.ID); err != nil {
+			log.Printf("Error updating session %s: %v", session.ID, err)
+		}
+	}
+
+	return claims, nil
+}
+
+// authenticateAPIToken validates a personal access token and stores its owner and scopes in the context
+func authenticateAPIToken(c *gin.Context, tokenString string) bool {
+	if !strings.HasPrefix(tokenString, models.APITokenPrefix) {
+		return false
+	}
+
+	apiToken, err := database.GetAPITokenByHash(models.HashAPIToken(tokenString))
+	if err != nil || apiToken.IsExpired() {
+		return false
+	}
+
+	// The token acts with the current role of its owner, narrowed by its scopes
+	user, err := database.GetUserByID(apiToken.UserID)
+	if err != nil {
+		return false
+	}
+
+	if err := database.TouchAPIToken(apiToken.ID); err != nil {
+		log.Printf("Error updating last use of API token %d: %v", apiToken.ID, err)
+	}
+
+	c.Set(ContextUserIDKey, user.ID)
+	c.Set(ContextUsernameKey, user.Username)
+	c.Set(ContextRoleKey, user.Role)
+	c.Set(ContextScopesKey, apiToken.ScopeList())
+	return true
+}
+
+// RequireRole only lets users with one of the given roles through.
+// It must be used after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := GetRole(c)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.Abort()
+	}
+}
+
+// RequireScope only lets personal access tokens carrying the given scope through.
+// Browser sessions are not scope restricted. It must be used after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, isToken := c.Get(ContextScopesKey)
+		if !isToken {
+			c.Next()
 			return
 		}
 
-		c.Next()
+		for _, granted := range scopes.([]string) {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Token is missing scope " + scope})
+		c.Abort()
 	}
 }
 
+// GetUserID returns the ID of the authenticated user set by AuthMiddleware
+func GetUserID(c *gin.Context) int {
+	return c.GetInt(ContextUserIDKey)
+}
+
+// GetUsername returns the name of the authenticated user set by AuthMiddleware
+func GetUsername(c *gin.Context) string {
+	return c.GetString(ContextUsernameKey)
+}
+
+// GetViewer returns who is making the request for paste access checks.
+// API tokens without the paste:read scope are treated as anonymous.
+func GetViewer(c *gin.Context) models.Viewer {
+	if scopes, isToken := c.Get(ContextScopesKey); isToken {
+		allowed := false
+		for _, granted := range scopes.([]string) {
+			if granted == models.ScopePasteRead {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return models.Viewer{}
+		}
+	}
+
+	return models.Viewer{UserID: GetUserID(c), Role: GetRole(c)}
+}
+
+// GetSessionID returns the login session of the request, empty for API token requests
+func GetSessionID(c *gin.Context) string {
+	return c.GetString(ContextSessionIDKey)
+}
+
+// GetRole returns the role of the authenticated user set by AuthMiddleware
+func GetRole(c *gin.Context) string {
+	return c.GetString(ContextRoleKey)
+}
+
 // GetCredentials returns username and password from environment variables
 func GetCredentials() (string, string) {
 	username := os.Getenv("ADMIN_USERNAME")
 	if username == "" {
 		username = "admin"
 	}
-	
+
 	password := os.Getenv("ADMIN_PASSWORD")
 	if password == "" {
 		password = "admin"
 	}
-	
+
 	return username, password
-}
\ No newline at end of file
+}
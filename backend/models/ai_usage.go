@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Sources of AI calls
+const (
+	AIUsageSourceClassify = "classify" // 后台任务为代码片段生成标题、简介、标签和语言
+	AIUsageSourceTest     = "test"     // 管理员通过 /api/test/ai 测试
+	AIUsageSourceExplain  = "explain"  // 用户通过 /api/paste/:id/explain 请求解释代码片段
+)
+
+// AIUsage records a single call made to the AI provider
+type AIUsage struct {
+	ID               int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Source           string    `json:"source" gorm:"not null;index"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PasteID          int       `json:"paste_id,omitempty" gorm:"index"` // 后台任务处理的代码片段，测试调用为 0
+	PromptTokens     int       `json:"prompt_tokens"`                   // 取自接口返回的 usage，接口未返回时为 0
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"` // 请求耗时 (毫秒)
+	Success          bool      `json:"success" gorm:"index"`
+	Error            string    `json:"error,omitempty"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+}
+
+// AIUsageDay aggregates the AI calls of one day (UTC)
+type AIUsageDay struct {
+	Date             string  `json:"date"`
+	Requests         int64   `json:"requests"`
+	Failed           int64   `json:"failed"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// AIBudget is the daily AI budget and how much of it was used today. A limit of 0 means unlimited.
+type AIBudget struct {
+	TokenLimit   int64 `json:"token_limit"`
+	RequestLimit int64 `json:"request_limit"`
+	TokensUsed   int64 `json:"tokens_used"`
+	RequestsUsed int64 `json:"requests_used"`
+	Exceeded     bool  `json:"exceeded"` // 超出后后台任务暂停，直到第二天 (UTC)
+}
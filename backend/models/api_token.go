@@ -0,0 +1,89 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// API token scopes
+const (
+	ScopePasteRead  = "paste:read"
+	ScopePasteWrite = "paste:write"
+	ScopeAdmin      = "admin"
+)
+
+// APITokenPrefix marks personal access tokens so they can be told apart from JWTs
+const APITokenPrefix = "pb_"
+
+// APIToken represents a personal access token used by scripts and CI
+type APIToken struct {
+	ID         int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     int        `json:"user_id" gorm:"index;not null"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"` // SHA-256 哈希，明文只在创建时返回一次
+	Prefix     string     `json:"prefix"`                        // 明文的前几位，便于识别
+	Scopes     string     `json:"scopes"`                        // 逗号分隔的权限范围
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// ScopeList returns the token scopes as a slice
+func (t *APIToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// IsExpired reports whether the token has passed its expiration time
+func (t *APIToken) IsExpired() bool {
+	return t.ExpiresAt != nil && !t.ExpiresAt.After(time.Now())
+}
+
+// CreateAPITokenRequest represents a request to create a personal access token
+type CreateAPITokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn string   `json:"expires_in"` // 如 30d、1w，为空或 never 表示永不过期
+}
+
+// IsValidScope reports whether the given scope is known
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopePasteRead, ScopePasteWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// RoleAllowsScope reports whether a user with the given role may grant the scope to a token
+func RoleAllowsScope(role, scope string) bool {
+	switch role {
+	case RoleAdmin:
+		return true
+	case RoleMember:
+		return scope != ScopeAdmin
+	default:
+		return scope == ScopePasteRead
+	}
+}
+
+// GenerateAPIToken creates a new random token and returns its plaintext and hash
+func GenerateAPIToken() (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token := APITokenPrefix + hex.EncodeToString(bytes)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken returns the hash stored for a token
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -16,6 +16,7 @@ type Config struct {
 // AIConfig represents AI settings
 type AIConfig struct {
 	Enabled     bool   `json:"enabled"`
+	Provider    string `json:"provider"`
 	BaseURL     string `json:"base_url"`
 	APIKey      string `json:"api_key"`
 	Model       string `json:"model"`
@@ -0,0 +1,33 @@
+package models
+
+// DiffLine represents a single line in a diff hunk
+type DiffLine struct {
+	Type    string `json:"type"` // context, add 或 delete
+	Content string `json:"content"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// DiffHunk represents a group of changed lines with surrounding context
+type DiffHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffSide identifies one side of a diff
+type DiffSide struct {
+	RandomID string `json:"random_id"`
+	Revision int    `json:"revision"`
+	Title    string `json:"title"`
+}
+
+// DiffResult represents the result of comparing two pastes
+type DiffResult struct {
+	A         DiffSide   `json:"a"`
+	B         DiffSide   `json:"b"`
+	Identical bool       `json:"identical"`
+	Hunks     []DiffHunk `json:"hunks"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// PasteExplanation is an AI explanation of one revision of a paste, cached so
+// repeated requests do not call the AI again
+type PasteExplanation struct {
+	ID        int                 `json:"-" gorm:"primaryKey;autoIncrement"`
+	PasteID   int                 `json:"-" gorm:"uniqueIndex:idx_paste_explanation;not null"`
+	Revision  int                 `json:"revision" gorm:"uniqueIndex:idx_paste_explanation;not null"` // 解释对应的代码片段版本
+	Model     string              `json:"model"`                                                      // 生成解释的模型
+	Summary   string              `json:"summary"`                                                    // 内容概述
+	Language  string              `json:"language"`                                                   // 模型判断的语言
+	Functions []ExplainedFunction `json:"functions" gorm:"serializer:json"`                           // 值得注意的函数
+	Bugs      []PotentialBug      `json:"bugs" gorm:"serializer:json"`                                // 可能存在的问题
+	CreatedAt time.Time           `json:"created_at" gorm:"autoCreateTime"`
+	Cached    bool                `json:"cached" gorm:"-"` // 是否来自缓存
+}
+
+// ExplainedFunction is a notable function described by an explanation
+type ExplainedFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PotentialBug is a possible problem pointed out by an explanation
+type PotentialBug struct {
+	Line        int    `json:"line,omitempty"` // 所在行号，无法确定时为 0
+	Severity    string `json:"severity"`       // low、medium 或 high
+	Description string `json:"description"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Job states
+const (
+	JobQueued    = "queued"    // 等待执行
+	JobRunning   = "running"   // 已被 worker 租用，租约到期未完成时会被重新执行
+	JobSucceeded = "succeeded" // 执行成功
+	JobFailed    = "failed"    // 执行失败，等待退避后重试
+	JobDead      = "dead"      // 重试次数用完，不再执行
+)
+
+// Job types
+const (
+	JobTypeClassifyPaste = "classify_paste" // AI 生成标题、简介、标签和语言
+)
+
+// Job is a unit of background work persisted so it survives restarts
+type Job struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Type        string     `json:"type" gorm:"not null;index"`
+	PasteID     int        `json:"paste_id" gorm:"index"`
+	State       string     `json:"state" gorm:"not null;index"`
+	Attempts    int        `json:"attempts" gorm:"default:0"`     // 已开始执行的次数
+	MaxAttempts int        `json:"max_attempts" gorm:"default:5"` // 最多执行次数，用完后进入 dead 状态
+	RunAt       time.Time  `json:"run_at" gorm:"index"`           // 最早可以执行的时间，重试时按指数退避推迟
+	LeasedUntil *time.Time `json:"leased_until"`                  // running 状态的租约到期时间
+	LastError   string     `json:"last_error"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	FinishedAt  *time.Time `json:"finished_at"` // 成功或进入 dead 状态的时间
+}
+
+// PasteClassification is the result of a classification job, applied on top of
+// whatever the owner changed while the job was running
+type PasteClassification struct {
+	Title       string   // 生成的标题，只在代码片段仍没有标题时使用
+	Description string   // 生成的简介
+	Language    string   // 识别的语言，只替换空语言或 plaintext
+	Tags        []string // 生成的标签，追加到已有标签之后
+}
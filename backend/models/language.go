@@ -0,0 +1,134 @@
+package models
+
+import (
+	"path"
+	"strings"
+)
+
+// Languages with special handling
+const (
+	LanguagePlaintext = "plaintext" // 未指定或无法识别时使用
+	LanguageMarkdown  = "markdown"  // 可渲染为 HTML
+)
+
+// Language describes a syntax language a paste can be tagged with
+type Language struct {
+	ID          string   // 与 highlight.js 的语言名一致
+	Extensions  []string // 对应的文件扩展名或文件名
+	ContentType string   // /raw 返回的 Content-Type
+}
+
+// languages lists the supported languages. Markup that a browser would render, scripts and
+// stylesheets are served as plain text, so a paste can never run in the site's origin or
+// be loaded by other sites as a script or stylesheet from this host.
+var languages = []Language{
+	{ID: LanguagePlaintext, Extensions: []string{".txt", ".log"}, ContentType: "text/plain"},
+	{ID: "bash", Extensions: []string{".sh", ".bash", ".zsh"}, ContentType: "text/x-shellscript"},
+	{ID: "c", Extensions: []string{".c", ".h"}, ContentType: "text/x-c"},
+	{ID: "cpp", Extensions: []string{".cpp", ".cc", ".cxx", ".hpp", ".hh"}, ContentType: "text/x-c++"},
+	{ID: "csharp", Extensions: []string{".cs"}, ContentType: "text/x-csharp"},
+	{ID: "css", Extensions: []string{".css"}, ContentType: "text/plain"},
+	{ID: "diff", Extensions: []string{".diff", ".patch"}, ContentType: "text/x-diff"},
+	{ID: "dockerfile", Extensions: []string{"dockerfile", ".dockerfile"}, ContentType: "text/x-dockerfile"},
+	{ID: "go", Extensions: []string{".go"}, ContentType: "text/x-go"},
+	{ID: "html", Extensions: []string{".html", ".htm"}, ContentType: "text/plain"},
+	{ID: "ini", Extensions: []string{".ini", ".cfg", ".conf", ".toml"}, ContentType: "text/plain"},
+	{ID: "java", Extensions: []string{".java"}, ContentType: "text/x-java"},
+	{ID: "javascript", Extensions: []string{".js", ".mjs", ".cjs", ".jsx"}, ContentType: "text/plain"},
+	{ID: "json", Extensions: []string{".json"}, ContentType: "application/json"},
+	{ID: "kotlin", Extensions: []string{".kt", ".kts"}, ContentType: "text/x-kotlin"},
+	{ID: "lua", Extensions: []string{".lua"}, ContentType: "text/x-lua"},
+	{ID: "makefile", Extensions: []string{"makefile", ".mk"}, ContentType: "text/x-makefile"},
+	{ID: LanguageMarkdown, Extensions: []string{".md", ".markdown"}, ContentType: "text/markdown"},
+	{ID: "perl", Extensions: []string{".pl", ".pm"}, ContentType: "text/x-perl"},
+	{ID: "php", Extensions: []string{".php"}, ContentType: "text/x-php"},
+	{ID: "powershell", Extensions: []string{".ps1", ".psm1"}, ContentType: "text/x-powershell"},
+	{ID: "python", Extensions: []string{".py", ".pyw"}, ContentType: "text/x-python"},
+	{ID: "ruby", Extensions: []string{".rb"}, ContentType: "text/x-ruby"},
+	{ID: "rust", Extensions: []string{".rs"}, ContentType: "text/x-rust"},
+	{ID: "sql", Extensions: []string{".sql"}, ContentType: "application/sql"},
+	{ID: "swift", Extensions: []string{".swift"}, ContentType: "text/x-swift"},
+	{ID: "typescript", Extensions: []string{".ts", ".tsx"}, ContentType: "text/x-typescript"},
+	{ID: "xml", Extensions: []string{".xml", ".svg", ".xsd"}, ContentType: "text/plain"},
+	{ID: "yaml", Extensions: []string{".yaml", ".yml"}, ContentType: "application/yaml"},
+}
+
+// languageAliases maps common alternative names to a language ID
+var languageAliases = map[string]string{
+	"c#":     "csharp",
+	"c++":    "cpp",
+	"docker": "dockerfile",
+	"golang": "go",
+	"js":     "javascript",
+	"kt":     "kotlin",
+	"make":   "makefile",
+	"md":     LanguageMarkdown,
+	"patch":  "diff",
+	"plain":  LanguagePlaintext,
+	"ps1":    "powershell",
+	"py":     "python",
+	"rb":     "ruby",
+	"rs":     "rust",
+	"sh":     "bash",
+	"shell":  "bash",
+	"text":   LanguagePlaintext,
+	"toml":   "ini",
+	"ts":     "typescript",
+	"txt":    LanguagePlaintext,
+	"yml":    "yaml",
+	"zsh":    "bash",
+}
+
+// NormalizeLanguage resolves a language name or alias to a supported language ID
+func NormalizeLanguage(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := languageAliases[name]; ok {
+		name = alias
+	}
+	if _, ok := LookupLanguage(name); ok {
+		return name, true
+	}
+	return "", false
+}
+
+// LanguageIDs returns the IDs of all supported languages
+func LanguageIDs() []string {
+	ids := make([]string, 0, len(languages))
+	for _, language := range languages {
+		ids = append(ids, language.ID)
+	}
+	return ids
+}
+
+// LookupLanguage returns the language with the given ID
+func LookupLanguage(id string) (Language, bool) {
+	for _, language := range languages {
+		if language.ID == id {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// LanguageForFilename returns the language matching a file name's extension, or the file name itself
+func LanguageForFilename(filename string) (string, bool) {
+	base := strings.ToLower(path.Base(strings.TrimSpace(filename)))
+	ext := path.Ext(base)
+	for _, language := range languages {
+		for _, candidate := range language.Extensions {
+			if candidate == base || (ext != "" && candidate == ext) {
+				return language.ID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// LanguageContentType returns the Content-Type used to serve content in the given language
+func LanguageContentType(id string) string {
+	language, ok := LookupLanguage(id)
+	if !ok {
+		language, _ = LookupLanguage(LanguagePlaintext)
+	}
+	return language.ContentType + "; charset=utf-8"
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLanguageContentType(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"go", "text/x-go; charset=utf-8"},
+		{"python", "text/x-python; charset=utf-8"},
+		{"json", "application/json; charset=utf-8"},
+		{LanguageMarkdown, "text/markdown; charset=utf-8"},
+		{LanguagePlaintext, "text/plain; charset=utf-8"},
+		{"html", "text/plain; charset=utf-8"},
+		{"xml", "text/plain; charset=utf-8"},
+		{"css", "text/plain; charset=utf-8"},
+		{"javascript", "text/plain; charset=utf-8"},
+		{"", "text/plain; charset=utf-8"},
+		{"brainfuck", "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		if got := LanguageContentType(tt.language); got != tt.want {
+			t.Errorf("LanguageContentType(%q) = %q, want %q", tt.language, got, tt.want)
+		}
+	}
+}
+
+// Content a browser renders, runs or applies must never be served from /raw with its own type
+func TestLanguageContentTypeIsInert(t *testing.T) {
+	active := []string{"html", "xml", "svg", "javascript", "ecmascript", "css"}
+	for _, language := range languages {
+		for _, kind := range active {
+			if strings.Contains(language.ContentType, kind) {
+				t.Errorf("language %q is served as %q", language.ID, language.ContentType)
+			}
+		}
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{"go", "go", true},
+		{" Python ", "python", true},
+		{"C++", "cpp", true},
+		{"yml", "yaml", true},
+		{"text", LanguagePlaintext, true},
+		{"brainfuck", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizeLanguage(tt.value)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("NormalizeLanguage(%q) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -1,36 +1,222 @@
 package models
 
 import (
-	"crypto/rand"
-	"math/big"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // Paste represents a paste entry
 type Paste struct {
-	ID               int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	RandomID         string    `json:"random_id" gorm:"uniqueIndex;not null"`
-	Title            string    `json:"title"`
-	Content          string    `json:"content" gorm:"not null"`
-	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
-	AITitleGenerated bool      `json:"ai_title_generated" gorm:"default:false"` // 是否已经AI生成过标题
-	AIRetryCount     int       `json:"ai_retry_count" gorm:"default:0"`         // AI生成重试次数
+	ID                int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	RandomID          string     `json:"random_id" gorm:"uniqueIndex;not null"`
+	Title             string     `json:"title"`
+	Content           string     `json:"content" gorm:"not null"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	AITitleGenerated  bool       `json:"ai_title_generated" gorm:"default:false"`           // 是否已经AI生成过标题
+	AIRetryCount      int        `json:"ai_retry_count" gorm:"default:0"`                   // AI生成重试次数
+	AIClassified      bool       `json:"ai_classified" gorm:"default:false;index"`          // 是否已由 AI 生成简介、标签和语言
+	Description       string     `json:"description,omitempty"`                             // AI 生成的简介
+	ExpiresAt         *time.Time `json:"expires_at" gorm:"index"`                           // 过期时间，为空表示永不过期
+	ExpiresIn         string     `json:"expires_in,omitempty" gorm:"-"`                     // 创建时指定的有效期，如 10m、1d、1w、never
+	ViewCount         int        `json:"view_count" gorm:"default:0"`                       // 已被读取的次数
+	MaxViews          int        `json:"max_views" gorm:"default:0"`                        // 最大读取次数，0 表示不限制
+	BurnAfterRead     bool       `json:"burn_after_read" gorm:"default:false"`              // 阅后即焚
+	Revision          int        `json:"revision" gorm:"default:1"`                         // 当前版本号
+	EditedAt          *time.Time `json:"edited_at"`                                         // 最后编辑时间
+	ParentID          *int       `json:"parent_id" gorm:"index"`                            // fork 来源的代码片段 ID
+	OwnerID           *int       `json:"owner_id" gorm:"index"`                             // 创建者用户 ID
+	Visibility        string     `json:"visibility" gorm:"not null;default:unlisted;index"` // public、unlisted 或 private
+	PasswordHash      string     `json:"-"`                                                 // 访问密码的 bcrypt 哈希，为空表示无密码
+	Password          string     `json:"password,omitempty" gorm:"-"`                       // 创建时指定的访问密码
+	PasswordProtected bool       `json:"password_protected" gorm:"-"`                       // 是否需要密码才能查看
+	Encrypted         bool       `json:"encrypted" gorm:"default:false;index"`              // 是否为客户端端到端加密，content 仅为密文
+	EncryptionFormat  string     `json:"encryption_format,omitempty"`                       // 加密格式，如 aes-256-gcm
+	EncryptionVersion int        `json:"encryption_version,omitempty"`                      // 加密格式版本
+	Slug              string     `json:"slug,omitempty" gorm:"-"`                           // 创建时指定的自定义短链接
+	Language          string     `json:"language" gorm:"index"`                             // 语法语言，为空表示尚未识别
+	Rendered          string     `json:"rendered,omitempty" gorm:"-"`                       // Markdown 代码片段渲染后的 HTML
+	Tags              []Tag      `json:"tags,omitempty" gorm:"many2many:paste_tags"`        // 标签，JSON 中为标签名数组
+
+	// Secrets found when the paste was created or edited, only part of that response
+	SecretFindings []SecretFinding `json:"secret_findings,omitempty" gorm:"-"`
+}
+
+// Encryption formats supported for client-side encrypted pastes
+const (
+	EncryptionFormatAESGCM = "aes-256-gcm" // content 为 base64(12 字节 IV || 密文 || 认证标签)，密钥保存在 URL 片段中
+)
+
+// EncryptedContentType is the content type of the envelope served for encrypted pastes on /raw
+const EncryptedContentType = "application/vnd.pastebin.encrypted+json"
+
+// EncryptedEnvelope wraps the ciphertext of an encrypted paste with what a client needs to decrypt it
+type EncryptedEnvelope struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// IsSupportedEncryption reports whether the encryption format and version are known
+func IsSupportedEncryption(format string, version int) bool {
+	return format == EncryptionFormatAESGCM && version == 1
+}
+
+// IsMarkdown reports whether the paste is rendered as Markdown
+func (p *Paste) IsMarkdown() bool {
+	return p.Language == LanguageMarkdown && p.CanInspectContent()
+}
+
+// CanInspectContent reports whether the server may look at the paste content,
+// encrypted pastes only hold ciphertext and are never inspected
+func (p *Paste) CanInspectContent() bool {
+	return !p.Encrypted
+}
+
+// Envelope returns the ciphertext envelope of an encrypted paste
+func (p *Paste) Envelope() EncryptedEnvelope {
+	return EncryptedEnvelope{
+		Format:     p.EncryptionFormat,
+		Version:    p.EncryptionVersion,
+		Ciphertext: p.Content,
+	}
+}
+
+// AfterFind marks pastes loaded from the database that require a password
+func (p *Paste) AfterFind(tx *gorm.DB) error {
+	p.PasswordProtected = p.PasswordHash != ""
+	return nil
+}
+
+// SetPassword hashes and stores the access password, an empty password removes it
+func (p *Paste) SetPassword(password string) error {
+	if password == "" {
+		p.PasswordHash = ""
+		p.PasswordProtected = false
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	p.PasswordHash = string(hash)
+	p.PasswordProtected = true
+	return nil
+}
+
+// CheckPassword reports whether the given password matches the access password
+func (p *Paste) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(p.PasswordHash), []byte(password)) == nil
+}
+
+// Paste visibilities
+const (
+	VisibilityPublic   = "public"   // 公开，出现在公共列表中
+	VisibilityUnlisted = "unlisted" // 不公开列出，知道链接即可访问
+	VisibilityPrivate  = "private"  // 仅创建者和管理员可访问
+)
+
+// IsValidVisibility reports whether the given visibility is known
+func IsValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// Viewer identifies who is reading a paste, the zero value is an anonymous visitor
+type Viewer struct {
+	UserID int
+	Role   string
+}
+
+// CanRead reports whether the viewer may read the paste
+func (v Viewer) CanRead(p *Paste) bool {
+	if p.Visibility != VisibilityPrivate {
+		return true
+	}
+	return v.isPrivileged(p)
+}
+
+// NeedsPassword reports whether the viewer has to supply the access password to read the paste
+func (v Viewer) NeedsPassword(p *Paste) bool {
+	return p.PasswordHash != "" && !v.isPrivileged(p)
+}
+
+// isPrivileged reports whether the viewer owns the paste or is an admin
+func (v Viewer) isPrivileged(p *Paste) bool {
+	return v.UserID != 0 && (p.IsOwnedBy(v.UserID) || v.Role == RoleAdmin)
+}
+
+// CurrentRevision returns the current content of the paste as a revision entry
+func (p *Paste) CurrentRevision() *PasteRevision {
+	createdAt := p.CreatedAt
+	if p.EditedAt != nil {
+		createdAt = *p.EditedAt
+	}
+	return &PasteRevision{
+		PasteID:   p.ID,
+		Revision:  p.Revision,
+		Title:     p.Title,
+		Content:   p.Content,
+		CreatedAt: createdAt,
+	}
 }
 
-// Base58 字符集（去掉了容易混淆的字符：0, O, I, l）
-const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+// IsOwnedBy reports whether the paste belongs to the given user
+func (p *Paste) IsOwnedBy(userID int) bool {
+	return p.OwnerID != nil && *p.OwnerID == userID
+}
 
-// GenerateRandomID 生成一个5位的base58随机ID
-func GenerateRandomID() (string, error) {
-	result := make([]byte, 4)
+// IsBurned reports whether the paste has used up all of its allowed views
+func (p *Paste) IsBurned() bool {
+	return p.MaxViews > 0 && p.ViewCount >= p.MaxViews
+}
+
+// IsExpired reports whether the paste has passed its expiration time
+func (p *Paste) IsExpired() bool {
+	return p.ExpiresAt != nil && !p.ExpiresAt.After(time.Now())
+}
 
-	for i := range result {
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(base58Alphabet))))
-		if err != nil {
-			return "", err
-		}
-		result[i] = base58Alphabet[num.Int64()]
+// MaxExpiration is the longest lifetime ParseExpiration accepts, longer ones would
+// overflow the duration and land in the past
+const MaxExpiration = 10 * 365 * 24 * time.Hour
+
+// ParseExpiration converts a duration string such as "10m", "1h", "1d", "1w" or "never"
+// into an absolute expiration time. An empty string or "never" yields nil.
+func ParseExpiration(value string) (*time.Time, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if value == "" || value == "never" {
+		return nil, nil
+	}
+
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("invalid expiration %q", value)
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid expiration %q", value)
+	}
+	if n > int(MaxExpiration/unit) {
+		return nil, fmt.Errorf("expiration %q is longer than 10 years", value)
 	}
 
-	return string(result), nil
+	expiresAt := time.Now().Add(time.Duration(n) * unit)
+	return &expiresAt, nil
 }
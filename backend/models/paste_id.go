@@ -0,0 +1,95 @@
+package models
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Base58 字符集（去掉了容易混淆的字符：0, O, I, l）
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Short ID settings, the length and alphabet can be changed through the paste_id_length and paste_id_alphabet configs
+const (
+	DefaultIDLength   = 8
+	DefaultIDAlphabet = base58Alphabet
+	MinIDLength       = 4
+	MaxIDLength       = 32
+	minIDAlphabetSize = 10
+)
+
+// Custom slug length limits
+const (
+	MinSlugLength = 3
+	MaxSlugLength = 64
+)
+
+// reservedIDs are top-level paths served by the application, no paste may use them as its short link
+var reservedIDs = map[string]bool{
+	"admin":    true,
+	"api":      true,
+	"assets":   true,
+	"auth":     true,
+	"index":    true,
+	"login":    true,
+	"logout":   true,
+	"md":       true,
+	"oauth2":   true,
+	"raw":      true,
+	"settings": true,
+	"static":   true,
+}
+
+// GenerateRandomID 生成一个指定长度的随机ID，字符取自给定的字符集
+func GenerateRandomID(length int, alphabet string) (string, error) {
+	result := make([]byte, length)
+
+	for i := range result {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = alphabet[num.Int64()]
+	}
+
+	return string(result), nil
+}
+
+// IsReservedID reports whether the short ID collides with an application route
+func IsReservedID(id string) bool {
+	return reservedIDs[strings.ToLower(id)]
+}
+
+// IsValidIDAlphabet reports whether the alphabet only has URL safe characters and enough distinct ones
+func IsValidIDAlphabet(alphabet string) bool {
+	seen := make(map[rune]bool)
+	for _, r := range alphabet {
+		if !isSlugChar(r) || seen[r] {
+			return false
+		}
+		seen[r] = true
+	}
+	return len(seen) >= minIDAlphabetSize
+}
+
+// ValidateSlug checks that a custom slug is usable as a short link
+func ValidateSlug(slug string) error {
+	if len(slug) < MinSlugLength || len(slug) > MaxSlugLength {
+		return fmt.Errorf("slug must be between %d and %d characters", MinSlugLength, MaxSlugLength)
+	}
+	for _, r := range slug {
+		if !isSlugChar(r) {
+			return fmt.Errorf("slug may only contain letters, digits, '-' and '_'")
+		}
+	}
+	if IsReservedID(slug) {
+		return fmt.Errorf("slug %q is reserved", slug)
+	}
+	return nil
+}
+
+// isSlugChar reports whether the character may appear in a short link
+func isSlugChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
+}
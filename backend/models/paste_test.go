@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseExpiration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration // 0 表示不过期
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "never", want: 0},
+		{value: " Never ", want: 0},
+		{value: "10m", want: 10 * time.Minute},
+		{value: "1H", want: time.Hour},
+		{value: "1d", want: 24 * time.Hour},
+		{value: "2w", want: 14 * 24 * time.Hour},
+		{value: "3650d", want: MaxExpiration},
+		{value: "521w", want: 521 * 7 * 24 * time.Hour},
+		{value: "3651d", wantErr: true},
+		{value: "522w", wantErr: true},
+		{value: "87601h", wantErr: true},
+		{value: "20000w", wantErr: true},
+		{value: "9223372036854775807m", wantErr: true},
+		{value: "99999999999999999999d", wantErr: true},
+		{value: "0d", wantErr: true},
+		{value: "-1d", wantErr: true},
+		{value: "1y", wantErr: true},
+		{value: "d", wantErr: true},
+		{value: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		before := time.Now()
+		got, err := ParseExpiration(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseExpiration(%q) = %v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseExpiration(%q) returned error %v", tt.value, err)
+			continue
+		}
+
+		if tt.want == 0 {
+			if got != nil {
+				t.Errorf("ParseExpiration(%q) = %v, want nil", tt.value, got)
+			}
+			continue
+		}
+		if got == nil || got.Sub(before) < tt.want || got.Sub(before) > tt.want+time.Minute {
+			t.Errorf("ParseExpiration(%q) = %v, want about %v from now", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// PasteRevision stores a previous version of a paste
+type PasteRevision struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PasteID   int       `json:"paste_id" gorm:"uniqueIndex:idx_paste_revision;not null"`
+	Revision  int       `json:"revision" gorm:"uniqueIndex:idx_paste_revision;not null"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"` // 该版本的创建时间
+}
+
+// UpdatePasteRequest represents a paste edit request
+type UpdatePasteRequest struct {
+	Title      *string   `json:"title"`
+	Content    *string   `json:"content"`
+	Visibility *string   `json:"visibility"`
+	Password   *string   `json:"password"` // 空字符串表示移除密码
+	Language   *string   `json:"language"` // 空字符串表示重新自动识别
+	Tags       *[]string `json:"tags"`     // 替换全部标签，空数组表示清除
+}
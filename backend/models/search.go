@@ -0,0 +1,9 @@
+package models
+
+// PasteSearchResult is a paste matched by a full-text search
+type PasteSearchResult struct {
+	Paste          `gorm:"embedded"`
+	TitleHighlight string  `json:"title_highlight"` // 标题，匹配词用 <mark> 标出，已做 HTML 转义
+	Snippet        string  `json:"snippet"`         // 内容中匹配位置附近的片段，格式同上
+	Score          float64 `json:"score"`           // bm25 相关度，越小越相关
+}
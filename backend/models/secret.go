@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Policies applied when a paste contains secrets
+const (
+	SecretPolicyOff     = "off"     // 不扫描
+	SecretPolicyBlock   = "block"   // 拒绝创建或编辑
+	SecretPolicyPrivate = "private" // 保存，但强制设为私有
+	SecretPolicyRedact  = "redact"  // 保存，匹配到的密钥替换为 [REDACTED]
+)
+
+// IsValidSecretPolicy reports whether the given secret policy is known
+func IsValidSecretPolicy(policy string) bool {
+	switch policy {
+	case SecretPolicyOff, SecretPolicyBlock, SecretPolicyPrivate, SecretPolicyRedact:
+		return true
+	}
+	return false
+}
+
+// SecretFinding is a secret detected in a paste. Only a masked preview of the secret is kept.
+type SecretFinding struct {
+	ID            int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PasteID       *int      `json:"-" gorm:"index"`             // 被拒绝的代码片段没有 ID
+	PasteRandomID string    `json:"paste_id,omitempty"`         // 代码片段短链接
+	Revision      int       `json:"revision,omitempty"`         // 发现密钥的版本
+	OwnerID       *int      `json:"owner_id" gorm:"index"`      // 提交者用户 ID
+	Rule          string    `json:"rule" gorm:"not null;index"` // 命中的规则，如 aws-access-key-id
+	Line          int       `json:"line"`                       // 所在行号，从 1 开始
+	Preview       string    `json:"preview"`                    // 打码后的密钥，只保留开头几个字符
+	Action        string    `json:"action" gorm:"not null"`     // 采取的处理方式：block、private 或 redact
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+	Start         int       `json:"-" gorm:"-"` // 密钥在内容中的字节位置，用于打码
+	End           int       `json:"-" gorm:"-"`
+}
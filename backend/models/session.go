@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session represents a login session backing a JWT, identified by the token's jti claim
+type Session struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	UserID     int       `json:"user_id" gorm:"index;not null"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index"`
+	Current    bool      `json:"current" gorm:"-"` // 是否为发起请求的会话
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tag limits
+const (
+	MaxTagLength    = 32
+	MaxTagsPerPaste = 10
+)
+
+// Tag labels pastes by project or topic
+type Tag struct {
+	ID        int       `json:"-" gorm:"primaryKey;autoIncrement"`
+	Name      string    `json:"-" gorm:"uniqueIndex;not null"` // 标签名，已统一为小写
+	CreatedAt time.Time `json:"-" gorm:"autoCreateTime"`
+}
+
+// TagCount is a tag together with the number of pastes carrying it
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TagFilter narrows a paste listing to pastes with the given tags
+type TagFilter struct {
+	Names    []string // 需要匹配的标签名
+	MatchAll bool     // true 表示必须包含所有标签 (AND)，false 表示包含任一标签即可 (OR)
+}
+
+// MarshalJSON encodes a tag as its name
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+// UnmarshalJSON decodes a tag from its name
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.Name)
+}
+
+// TagsFromNames wraps tag names into tags
+func TagsFromNames(names []string) []Tag {
+	tags := make([]Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, Tag{Name: name})
+	}
+	return tags
+}
+
+// TagNames returns the names of the paste's tags
+func (p *Paste) TagNames() []string {
+	names := make([]string, 0, len(p.Tags))
+	for _, tag := range p.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// NormalizeTagNames lowercases, validates and deduplicates tag names, keeping their order
+func NormalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if err := validateTagName(name); err != nil {
+			return nil, err
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+
+	if len(normalized) > MaxTagsPerPaste {
+		return nil, fmt.Errorf("a paste can have at most %d tags", MaxTagsPerPaste)
+	}
+	return normalized, nil
+}
+
+// validateTagName checks that a tag is made of letters, digits and - _ . + #
+func validateTagName(name string) error {
+	if utf8.RuneCountInString(name) > MaxTagLength {
+		return fmt.Errorf("tag %q is longer than %d characters", name, MaxTagLength)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_.+#", r) {
+			return fmt.Errorf("tag %q may only contain letters, digits and - _ . + #", name)
+		}
+	}
+	return nil
+}
+
+// MergeTagNames appends extra tags to existing ones, skipping invalid and duplicate names,
+// until a paste carries MaxTagsPerPaste tags
+func MergeTagNames(existing, extra []string) []string {
+	names := existing
+	for _, name := range extra {
+		if len(names) >= MaxTagsPerPaste {
+			break
+		}
+		if normalized, err := NormalizeTagNames(append(append([]string{}, names...), name)); err == nil {
+			names = normalized
+		}
+	}
+	return names
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeTagNames(t *testing.T) {
+	ten := make([]string, 0, MaxTagsPerPaste)
+	for i := range MaxTagsPerPaste {
+		ten = append(ten, fmt.Sprintf("tag%d", i))
+	}
+
+	tests := []struct {
+		name     string
+		existing []string
+		extra    []string
+		want     []string
+	}{
+		{name: "adds after the existing tags", existing: []string{"ops"}, extra: []string{"nginx", "proxy"}, want: []string{"ops", "nginx", "proxy"}},
+		{name: "no existing tags", existing: []string{}, extra: []string{"Go", "CLI"}, want: []string{"go", "cli"}},
+		{name: "skips duplicates", existing: []string{"nginx"}, extra: []string{"NGINX", "proxy", "proxy"}, want: []string{"nginx", "proxy"}},
+		{name: "skips invalid names", existing: []string{"ops"}, extra: []string{"web server", "c++", strings.Repeat("x", MaxTagLength+1), ""}, want: []string{"ops", "c++"}},
+		{name: "stops at the maximum", existing: ten[:9], extra: []string{"a", "b"}, want: append(append([]string{}, ten[:9]...), "a")},
+		{name: "full paste keeps its tags", existing: ten, extra: []string{"a"}, want: ten},
+		{name: "nothing to add", existing: []string{"ops"}, extra: nil, want: []string{"ops"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := append([]string{}, tt.existing...)
+			got := MergeTagNames(existing, tt.extra)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeTagNames(%v, %v) = %v, want %v", tt.existing, tt.extra, got, tt.want)
+			}
+			if !reflect.DeepEqual(existing, tt.existing) {
+				t.Errorf("existing tags were modified to %v", existing)
+			}
+		})
+	}
+}
@@ -1,13 +1,82 @@
 package models
 
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User providers
+const (
+	UserProviderLocal  = "local"
+	UserProviderOAuth2 = "oauth2"
+)
+
+// User roles
+const (
+	RoleAdmin    = "admin"
+	RoleMember   = "member"
+	RoleReadOnly = "read-only"
+)
+
+// IsValidRole reports whether the given role is known
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleMember, RoleReadOnly:
+		return true
+	}
+	return false
+}
+
 // User represents a user in the system
 type User struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	ID           int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Username     string    `json:"username" gorm:"uniqueIndex:idx_user_provider_username;not null"`
+	PasswordHash string    `json:"-"`                                                               // bcrypt 哈希，仅本地用户使用
+	Provider     string    `json:"provider" gorm:"uniqueIndex:idx_user_provider_username;not null"` // local 或 oauth2
+	ProviderID   string    `json:"-" gorm:"index"`                                                  // OAuth2 提供方的用户标识
+	Role         string    `json:"role" gorm:"not null;default:member"`                             // admin、member 或 read-only
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// SetPassword hashes and stores the given password
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckPassword reports whether the given password matches the stored hash
+func (u *User) CheckPassword(password string) bool {
+	if u.PasswordHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
 }
 
 // LoginRequest represents login request data
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
-}
\ No newline at end of file
+}
+
+// CreateUserRequest represents a request to create a local user
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// ChangePasswordRequest represents a password change request
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// UpdateRoleRequest represents a request to change a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
@@ -1,8 +1,13 @@
 package routes
 
 import (
+	"log"
+	"os"
+	"strings"
+
 	"pastebin/controllers"
 	"pastebin/middleware"
+	"pastebin/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,50 +16,111 @@ import (
 func SetupRoutes() *gin.Engine {
 	router := gin.Default()
 
+	// Only reverse proxies listed in TRUSTED_PROXIES may set the client IP. Password attempts
+	// are limited per client IP, a forged X-Forwarded-For header must not lift the limit.
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Printf("Invalid TRUSTED_PROXIES, no proxy is trusted: %v", err)
+		router.SetTrustedProxies(nil)
+	}
+
 	// Serve frontend static files
 	router.Static("/static", "../frontend")
 	router.StaticFile("/", "../frontend/index.html")
 
 	// Raw paste endpoint (before the general /:id route)
-	router.GET("/raw/:id", controllers.GetRawPasteHandler)
+	router.GET("/raw/:id", middleware.OptionalAuthMiddleware(), controllers.GetRawPasteHandler)
+	router.GET("/raw/diff/:a/:b", middleware.OptionalAuthMiddleware(), controllers.RawDiffHandler)
+	router.GET("/md/:id", middleware.OptionalAuthMiddleware(), controllers.MarkdownPasteHandler)
 
 	// Route for short links
-	router.GET("/:id", controllers.ViewPasteHandler)
+	router.GET("/:id", middleware.OptionalAuthMiddleware(), controllers.ViewPasteHandler)
 
 	// Settings page route
 	router.GET("/settings", func(c *gin.Context) {
 		c.File("../frontend/settings.html")
 	})
 
+	// Role and token scope requirements, used after AuthMiddleware
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+	canWrite := middleware.RequireRole(models.RoleAdmin, models.RoleMember)
+	adminScope := middleware.RequireScope(models.ScopeAdmin)
+	writeScope := middleware.RequireScope(models.ScopePasteWrite)
+	readScope := middleware.RequireScope(models.ScopePasteRead)
+
 	// Auth endpoints
 	router.POST("/api/login", controllers.LoginHandler)
 	router.POST("/api/logout", middleware.AuthMiddleware(), controllers.LogoutHandler) // Protected
 	router.GET("/api/auth/check", controllers.CheckAuthHandler)
 
+	// User endpoints
+	router.GET("/api/users", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetUsersHandler)                                // Admin
+	router.POST("/api/users", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.CreateUserHandler)                             // Admin
+	router.PUT("/api/users/:id/role", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.UpdateUserRoleHandler)                 // Admin
+	router.POST("/api/users/:id/sessions/revoke", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.RevokeUserSessionsHandler) // Admin
+	router.PUT("/api/user/password", middleware.AuthMiddleware(), adminScope, controllers.ChangePasswordHandler)                             // Protected
+
+	// Admin endpoints
+	router.POST("/api/admin/jwt/rotate", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.RotateJWTKeyHandler)  // Admin
+	router.GET("/api/admin/secrets", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetSecretFindingsHandler) // Admin
+
+	// Session endpoints
+	router.GET("/api/sessions", middleware.AuthMiddleware(), adminScope, controllers.GetSessionsHandler)          // Protected
+	router.DELETE("/api/sessions/:id", middleware.AuthMiddleware(), adminScope, controllers.RevokeSessionHandler) // Protected
+
+	// Personal access token endpoints
+	router.GET("/api/tokens", middleware.AuthMiddleware(), adminScope, controllers.GetAPITokensHandler)          // Protected
+	router.POST("/api/tokens", middleware.AuthMiddleware(), adminScope, controllers.CreateAPITokenHandler)       // Protected
+	router.DELETE("/api/tokens/:id", middleware.AuthMiddleware(), adminScope, controllers.DeleteAPITokenHandler) // Protected
+
 	// OAuth2 endpoints
 	router.GET("/api/oauth2/login", controllers.OAuth2LoginHandler)
 	router.GET("/api/oauth2/callback", controllers.OAuth2CallbackHandler)
 	router.GET("/api/oauth2/status", controllers.CheckOAuth2StatusHandler)
 
 	// Configuration endpoints
-	router.GET("/api/configs", middleware.AuthMiddleware(), controllers.GetConfigsHandler)                           // Protected
-	router.GET("/api/configs/:category", middleware.AuthMiddleware(), controllers.GetConfigsByCategoryHandler)       // Protected
-	router.PUT("/api/config", middleware.AuthMiddleware(), controllers.UpdateConfigHandler)                          // Protected
-	router.GET("/api/config/ai", middleware.AuthMiddleware(), controllers.GetAIConfigHandler)                        // Protected
-	router.PUT("/api/config/ai", middleware.AuthMiddleware(), controllers.UpdateAIConfigHandler)                     // Protected
-	router.GET("/api/config/oauth2", middleware.AuthMiddleware(), controllers.GetOAuth2ConfigHandler)               // Protected
-	router.PUT("/api/config/oauth2", middleware.AuthMiddleware(), controllers.UpdateOAuth2ConfigHandler)            // Protected
+	router.GET("/api/configs", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetConfigsHandler)                     // Admin
+	router.GET("/api/configs/:category", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetConfigsByCategoryHandler) // Admin
+	router.PUT("/api/config", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.UpdateConfigHandler)                    // Admin
+	router.GET("/api/config/ai", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetAIConfigHandler)                  // Admin
+	router.PUT("/api/config/ai", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.UpdateAIConfigHandler)               // Admin
+	router.GET("/api/config/oauth2", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetOAuth2ConfigHandler)          // Admin
+	router.PUT("/api/config/oauth2", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.UpdateOAuth2ConfigHandler)       // Admin
 
 	// Test endpoints
-	router.POST("/api/test/ai", middleware.AuthMiddleware(), controllers.TestAIHandler)                             // Protected
-	router.GET("/api/models", middleware.AuthMiddleware(), controllers.GetModelsHandler)                           // Protected
+	router.POST("/api/test/ai", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.TestAIHandler)     // Admin
+	router.GET("/api/models", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetModelsHandler)    // Admin
+	router.GET("/api/ai/health", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.AIHealthHandler)  // Admin
+	router.GET("/api/ai/jobs", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetAIJobsHandler)   // Admin
+	router.GET("/api/ai/usage", middleware.AuthMiddleware(), adminOnly, adminScope, controllers.GetAIUsageHandler) // Admin
 
 	// API endpoints
-	router.POST("/api/paste", middleware.AuthMiddleware(), controllers.CreatePasteHandler)                       // Protected
-	router.GET("/api/paste/:id", controllers.GetPasteHandler)                                                    // Protected
-	router.GET("/api/pastes", middleware.AuthMiddleware(), controllers.GetAllPastesHandler)                      // Protected
-	router.GET("/api/pastes/paginated", middleware.AuthMiddleware(), controllers.GetPastesWithPaginationHandler) // Protected
-	router.DELETE("/api/paste/:id", middleware.AuthMiddleware(), controllers.DeletePasteHandler)                 // Protected
+	router.POST("/api/paste", middleware.AuthMiddleware(), canWrite, writeScope, controllers.CreatePasteHandler)    // Protected
+	router.GET("/api/paste/:id", middleware.OptionalAuthMiddleware(), controllers.GetPasteHandler)                  // Protected
+	router.PUT("/api/paste/:id", middleware.AuthMiddleware(), canWrite, writeScope, controllers.UpdatePasteHandler) // Protected
+	router.GET("/api/paste/:id/revisions", middleware.OptionalAuthMiddleware(), controllers.GetPasteRevisionsHandler)
+	router.GET("/api/paste/:id/revisions/:n", middleware.OptionalAuthMiddleware(), controllers.GetPasteRevisionHandler)
+	router.POST("/api/paste/:id/fork", middleware.AuthMiddleware(), canWrite, writeScope, controllers.ForkPasteHandler) // Protected
+	router.POST("/api/paste/:id/explain", middleware.AuthMiddleware(), readScope, controllers.ExplainPasteHandler)      // Protected
+	router.GET("/api/paste/:id/html", middleware.OptionalAuthMiddleware(), controllers.GetPasteHTMLHandler)
+	router.GET("/api/paste/:id/forks", middleware.OptionalAuthMiddleware(), controllers.GetPasteForksHandler)
+	router.GET("/api/pastes", middleware.AuthMiddleware(), readScope, controllers.GetAllPastesHandler) // Protected
+	router.GET("/api/pastes/public", controllers.GetPublicPastesHandler)
+	router.GET("/api/pastes/paginated", middleware.AuthMiddleware(), readScope, controllers.GetPastesWithPaginationHandler) // Protected
+	router.GET("/api/pastes/search", middleware.AuthMiddleware(), readScope, controllers.SearchPastesHandler)               // Protected
+	router.GET("/api/tags", middleware.AuthMiddleware(), readScope, controllers.GetTagsHandler)                             // Protected
+	router.DELETE("/api/paste/:id", middleware.AuthMiddleware(), canWrite, writeScope, controllers.DeletePasteHandler)      // Protected
+	router.GET("/api/diff", middleware.OptionalAuthMiddleware(), controllers.DiffHandler)
 
 	return router
 }
+
+// trustedProxies returns the comma separated IPs and CIDRs from TRUSTED_PROXIES, nil when unset
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
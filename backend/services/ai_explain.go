@@ -0,0 +1,169 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pastebin/database"
+	"pastebin/models"
+)
+
+// Limits applied to explanations
+const (
+	// maxExplainContentLength bounds how much of a paste is sent to the AI, in characters
+	maxExplainContentLength = 20000
+	// minExplanationTokens keeps the JSON answer from being cut off by a small ai_max_tokens
+	minExplanationTokens   = 1000
+	maxExplanationSummary  = 1000
+	maxExplainedFunctions  = 20
+	maxPotentialBugs       = 20
+	maxExplanationItemText = 300
+)
+
+// Errors returned when a paste cannot be explained right now
+var (
+	ErrAINotEnabled     = errors.New("AI is not enabled")
+	ErrAIBudgetExceeded = errors.New("AI daily budget exceeded")
+	// ErrPasteKeepsSecrets keeps secrets the policy left in a paste from reaching the AI provider
+	ErrPasteKeepsSecrets = errors.New("paste contains secrets")
+)
+
+// explainLocks serializes explanations of the same paste revision, so concurrent requests
+// make a single AI call and the ones waiting are served its cached result
+var explainLocks = newKeyedMutex()
+
+// explanationPrompt asks for an explanation the answer parser understands
+const explanationPrompt = `You are a senior engineer. Explain the code or text given as JSON in the user message for a reader seeing it for the first time.
+Write the texts in Simplified Chinese and answer with a single JSON object and nothing else:
+{"summary": "what the content does, at most a few sentences", "language": "programming or markup language", "functions": [{"name": "notable function, class or section", "description": "what it does"}], "bugs": [{"line": 0, "severity": "low, medium or high", "description": "a potential bug, security issue or edge case"}]}
+Use an empty list when there are no notable functions or potential bugs, and line 0 when a bug has no single line.`
+
+// explainRequest is the user message of an explanation request
+type explainRequest struct {
+	Title     string `json:"title,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated,omitempty"` // 内容过长，只发送了开头部分
+}
+
+// ExplainPaste returns an explanation of the paste's current revision. An explanation is
+// generated once per revision and served from the cache afterwards, uncached requests
+// fail with ErrAINotEnabled or ErrAIBudgetExceeded when the AI cannot be called. Pastes
+// with unredacted secrets are never explained and fail with ErrPasteKeepsSecrets.
+func (s *AIService) ExplainPaste(paste *models.Paste) (*models.PasteExplanation, error) {
+	keepsSecrets, err := database.PasteKeepsSecrets(paste.ID)
+	if err != nil {
+		return nil, err
+	}
+	if keepsSecrets {
+		return nil, ErrPasteKeepsSecrets
+	}
+
+	unlock := explainLocks.Lock(strconv.Itoa(paste.ID) + ":" + strconv.Itoa(paste.Revision))
+	defer unlock()
+
+	cached, err := database.GetPasteExplanation(paste.ID, paste.Revision)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil {
+		cached.Cached = true
+		return cached, nil
+	}
+
+	settings, err := s.loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		return nil, ErrAINotEnabled
+	}
+
+	budget, err := s.Budget()
+	if err != nil {
+		return nil, err
+	}
+	if budget.Exceeded {
+		return nil, ErrAIBudgetExceeded
+	}
+
+	content := truncateRunes(paste.Content, maxExplainContentLength)
+	userInput, err := json.Marshal(explainRequest{
+		Title:     paste.Title,
+		Language:  paste.Language,
+		Content:   content,
+		Truncated: len(content) < len(paste.Content),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	var explanation *models.PasteExplanation
+	usage := &models.AIUsage{Source: models.AIUsageSourceExplain, PasteID: paste.ID}
+	err = s.complete(settings, usage, explanationPrompt, string(userInput), minExplanationTokens,
+		func(text string) error {
+			var err error
+			explanation, err = parseExplanation(text)
+			return err
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	explanation.PasteID = paste.ID
+	explanation.Revision = paste.Revision
+	explanation.Model = usage.Model
+	return database.SavePasteExplanation(explanation)
+}
+
+// parseExplanation parses the JSON answer of the model and applies the length limits
+func parseExplanation(text string) (*models.PasteExplanation, error) {
+	responseContent := stripCodeFence(strings.TrimSpace(text))
+	if responseContent == "" {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	var explanation models.PasteExplanation
+	if err := json.Unmarshal([]byte(responseContent), &explanation); err != nil {
+		return nil, fmt.Errorf("failed to parse AI response as JSON: %v", err)
+	}
+
+	explanation.Summary = truncateRunes(strings.TrimSpace(explanation.Summary), maxExplanationSummary)
+	if language, ok := models.NormalizeLanguage(explanation.Language); ok {
+		explanation.Language = language
+	}
+
+	functions := make([]models.ExplainedFunction, 0, len(explanation.Functions))
+	for _, function := range explanation.Functions {
+		if len(functions) >= maxExplainedFunctions {
+			break
+		}
+		function.Name = truncateRunes(strings.TrimSpace(function.Name), maxExplanationItemText)
+		function.Description = truncateRunes(strings.TrimSpace(function.Description), maxExplanationItemText)
+		if function.Name != "" {
+			functions = append(functions, function)
+		}
+	}
+	explanation.Functions = functions
+
+	bugs := make([]models.PotentialBug, 0, len(explanation.Bugs))
+	for _, bug := range explanation.Bugs {
+		if len(bugs) >= maxPotentialBugs {
+			break
+		}
+		bug.Description = truncateRunes(strings.TrimSpace(bug.Description), maxExplanationItemText)
+		bug.Severity = strings.ToLower(strings.TrimSpace(bug.Severity))
+		if bug.Line < 0 {
+			bug.Line = 0
+		}
+		if bug.Description != "" {
+			bugs = append(bugs, bug)
+		}
+	}
+	explanation.Bugs = bugs
+
+	return &explanation, nil
+}
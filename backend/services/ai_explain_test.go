@@ -0,0 +1,143 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseExplanation(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		wantErr       bool
+		wantSummary   string
+		wantLanguage  string
+		wantFunctions int
+		wantBugs      int
+	}{
+		{
+			name:          "plain JSON",
+			text:          `{"summary": " 打印一个数字 ", "language": "Python", "functions": [{"name": "main", "description": "入口"}], "bugs": []}`,
+			wantSummary:   "打印一个数字",
+			wantLanguage:  "python",
+			wantFunctions: 1,
+		},
+		{
+			name:         "code fence",
+			text:         "```json\n{\"summary\": \"s\", \"language\": \"golang\"}\n```",
+			wantSummary:  "s",
+			wantLanguage: "go",
+		},
+		{
+			name:         "unknown language is kept",
+			text:         `{"summary": "s", "language": "cobol"}`,
+			wantSummary:  "s",
+			wantLanguage: "cobol",
+		},
+		{
+			name:          "entries without a name or description are dropped",
+			text:          `{"summary": "s", "functions": [{"name": " "}, {"name": "f"}], "bugs": [{"line": 3, "description": ""}, {"line": -1, "severity": "HIGH", "description": "d"}]}`,
+			wantSummary:   "s",
+			wantFunctions: 1,
+			wantBugs:      1,
+		},
+		{
+			name:          "lists are capped",
+			text:          `{"summary": "s", "functions": [` + strings.Repeat(`{"name": "f"},`, 30) + `{"name": "f"}]}`,
+			wantSummary:   "s",
+			wantFunctions: maxExplainedFunctions,
+		},
+		{name: "empty answer", text: "  ", wantErr: true},
+		{name: "not JSON", text: "这段代码打印一个数字", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation, err := parseExplanation(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseExplanation() = %+v, want an error", explanation)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if explanation.Summary != tt.wantSummary {
+				t.Errorf("summary = %q, want %q", explanation.Summary, tt.wantSummary)
+			}
+			if explanation.Language != tt.wantLanguage {
+				t.Errorf("language = %q, want %q", explanation.Language, tt.wantLanguage)
+			}
+			if len(explanation.Functions) != tt.wantFunctions {
+				t.Errorf("%d functions, want %d", len(explanation.Functions), tt.wantFunctions)
+			}
+			if len(explanation.Bugs) != tt.wantBugs {
+				t.Errorf("%d bugs, want %d", len(explanation.Bugs), tt.wantBugs)
+			}
+			for _, bug := range explanation.Bugs {
+				if bug.Line < 0 || bug.Severity != strings.ToLower(bug.Severity) {
+					t.Errorf("bug not normalized: %+v", bug)
+				}
+			}
+		})
+	}
+}
+
+func TestParseExplanationTruncatesSummary(t *testing.T) {
+	explanation, err := parseExplanation(`{"summary": "` + strings.Repeat("长", maxExplanationSummary+10) + `"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len([]rune(explanation.Summary)); got != maxExplanationSummary {
+		t.Errorf("summary has %d characters, want %d", got, maxExplanationSummary)
+	}
+}
+
+// Explanations of one revision run one at a time, other revisions are not held up
+func TestKeyedMutex(t *testing.T) {
+	locks := newKeyedMutex()
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.Lock("1:1")
+			defer unlock()
+
+			now := running.Add(1)
+			if now > maxRunning.Load() {
+				maxRunning.Store(now)
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+
+	// A different key is available while the first one is held
+	unlock := locks.Lock("1:1")
+	done := make(chan struct{})
+	go func() {
+		locks.Lock("1:2")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("lock of another key waited for a held key")
+	}
+	unlock()
+
+	wg.Wait()
+	if got := maxRunning.Load(); got != 1 {
+		t.Errorf("%d holders of the same key at once, want 1", got)
+	}
+	if len(locks.locks) != 0 {
+		t.Errorf("%d locks left after every holder released them", len(locks.locks))
+	}
+}
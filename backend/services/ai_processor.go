@@ -1,21 +1,49 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"pastebin/database"
 	"pastebin/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// aiJobLease is how long a worker owns a job, longer than any AI request may take
+	aiJobLease = 2 * time.Minute
+	// aiJobPollInterval bounds how late retries and expired leases are noticed
+	aiJobPollInterval = 5 * time.Second
+	// Retries wait aiRetryBaseDelay, doubling after every failed attempt up to aiRetryMaxDelay
+	aiRetryBaseDelay = 30 * time.Second
+	aiRetryMaxDelay  = time.Hour
+
+	defaultAIWorkers     = 2
+	maxAIWorkers         = 16
+	defaultAIMaxAttempts = 5
 )
 
-// AIProcessorService handles background AI title generation
+// errAIDisabled is returned when a job runs while AI was switched off
+var errAIDisabled = errors.New("AI is disabled")
+
+// aiJobSignal wakes the dispatcher when a job was queued or a worker became free
+var aiJobSignal = make(chan struct{}, 1)
+
+// AIProcessorService runs queued AI jobs on a bounded pool of workers
 type AIProcessorService struct {
 	aiService *AIService
 	mutex     sync.Mutex
 	running   bool
 	stopChan  chan bool
+	workers   sync.WaitGroup
+	// budgetExceeded is only used by the dispatcher to log pausing and resuming once
+	budgetExceeded bool
 }
 
 // NewAIProcessorService creates a new AI processor service
@@ -36,13 +64,21 @@ func (s *AIProcessorService) Start() {
 	s.running = true
 	s.mutex.Unlock()
 
-	log.Println("AI Processor Service started")
-	
-	// Start the processing goroutine
-	go s.processLoop()
+	// Queue pastes that were never classified, e.g. those created before the queue existed
+	queued, err := database.EnqueueUnclassifiedPastes(aiMaxAttempts())
+	if err != nil {
+		log.Printf("Error queueing unclassified pastes: %v", err)
+	} else if queued > 0 {
+		log.Printf("Queued %d unclassified pastes for AI processing", queued)
+	}
+
+	workers := aiWorkers()
+	log.Printf("AI Processor Service started with %d workers", workers)
+
+	go s.processLoop(workers)
 }
 
-// Stop halts the background AI processing
+// Stop halts the background AI processing and waits for running jobs to finish
 func (s *AIProcessorService) Stop() {
 	s.mutex.Lock()
 	if !s.running {
@@ -54,96 +90,278 @@ func (s *AIProcessorService) Stop() {
 
 	log.Println("Stopping AI Processor Service...")
 	s.stopChan <- true
+	s.workers.Wait()
+	log.Println("AI Processor Service stopped")
+}
+
+// EnqueuePasteClassification queues a paste for AI classification and wakes a worker.
+// Failing to queue is only logged, the paste is queued again on the next start.
+func EnqueuePasteClassification(paste *models.Paste) {
+	if paste.AIClassified || !paste.CanInspectContent() || paste.PasswordHash != "" {
+		return
+	}
+
+	job := &models.Job{
+		Type:        models.JobTypeClassifyPaste,
+		PasteID:     paste.ID,
+		MaxAttempts: aiMaxAttempts(),
+	}
+	if err := database.EnqueueJob(job); err != nil {
+		log.Printf("Error queueing paste %d for AI processing: %v", paste.ID, err)
+		return
+	}
+	wakeAIWorkers()
+}
+
+// RequeuePasteClassification queues a paste whose classification was skipped, e.g. because
+// it had a password that was removed since. Pastes that need no classification are ignored.
+func RequeuePasteClassification(pasteID int) {
+	queued, err := database.EnqueuePasteIfUnclassified(pasteID, aiMaxAttempts())
+	if err != nil {
+		log.Printf("Error queueing paste %d for AI processing: %v", pasteID, err)
+		return
+	}
+	if queued {
+		wakeAIWorkers()
+	}
 }
 
-// ProcessPasteAsync adds a paste to be processed asynchronously
-func (s *AIProcessorService) ProcessPasteAsync(pasteID int) {
-	// This function is called after creating a paste
-	// Since we now process in batches, we don't need to do anything here
-	// The scheduled task will pick up the paste automatically
-	log.Printf("Paste %d queued for AI processing", pasteID)
+// wakeAIWorkers signals the dispatcher without blocking, one pending signal is enough
+func wakeAIWorkers() {
+	select {
+	case aiJobSignal <- struct{}{}:
+	default:
+	}
 }
 
-// processLoop runs the main processing loop
-func (s *AIProcessorService) processLoop() {
-	ticker := time.NewTicker(5 * time.Second) // Every 10 seconds
+// processLoop hands out jobs whenever one is queued, a worker frees up or the poll interval passes
+func (s *AIProcessorService) processLoop(workers int) {
+	ticker := time.NewTicker(aiJobPollInterval)
 	defer ticker.Stop()
 
+	slots := make(chan struct{}, workers)
+	s.dispatch(slots)
+
 	for {
 		select {
 		case <-s.stopChan:
-			log.Println("AI Processor Service stopped")
 			return
 		case <-ticker.C:
-			s.processPendingPastes()
+		case <-aiJobSignal:
 		}
+		s.dispatch(slots)
 	}
 }
 
-// processPendingPastes processes pastes that need AI title generation
-func (s *AIProcessorService) processPendingPastes() {
-	// Use mutex to ensure only one processing operation at a time
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// dispatch claims jobs until every worker is busy or nothing is left to run
+func (s *AIProcessorService) dispatch(slots chan struct{}) {
+	// Jobs stay queued while AI is off and run once it is enabled
+	if !s.aiService.Enabled() {
+		return
+	}
 
-	// Get pastes that need processing
-	pastes, err := database.GetPastesForAIProcessing()
+	// Jobs also stay queued once the daily budget is used up and run again the next day.
+	// Running jobs are not interrupted, so the budget may be exceeded by up to one call per worker.
+	budget, err := s.aiService.Budget()
 	if err != nil {
-		log.Printf("Error getting pastes for AI processing: %v", err)
+		log.Printf("Error checking AI budget: %v", err)
+		return
+	}
+	if budget.Exceeded != s.budgetExceeded {
+		s.budgetExceeded = budget.Exceeded
+		if budget.Exceeded {
+			log.Printf("AI daily budget exceeded (%d/%d tokens, %d/%d requests), pausing AI jobs",
+				budget.TokensUsed, budget.TokenLimit, budget.RequestsUsed, budget.RequestLimit)
+		} else {
+			log.Println("AI daily budget available again, resuming AI jobs")
+		}
+	}
+	if budget.Exceeded {
 		return
 	}
 
-	if len(pastes) == 0 {
-		return // No pastes to process
+	if buried, err := database.BuryAbandonedJobs(); err != nil {
+		log.Printf("Error burying abandoned AI jobs: %v", err)
+	} else if buried > 0 {
+		log.Printf("Gave up %d AI jobs whose last attempt never finished", buried)
 	}
 
-	log.Printf("Processing %d pastes for AI title generation", len(pastes))
+	for {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return // Every worker is busy
+		}
 
-	for _, paste := range pastes {
-		err := s.processPaste(&paste)
-		if err != nil {
-			log.Printf("Error processing paste %d: %v", paste.ID, err)
-			// Increment retry count
-			database.IncrementPasteRetryCount(paste.ID)
+		job, err := database.ClaimJob(aiJobLease)
+		if err != nil || job == nil {
+			<-slots
+			if err != nil {
+				log.Printf("Error claiming AI job: %v", err)
+			}
+			return
 		}
-		
-		// Small delay between processing each paste
-		time.Sleep(100 * time.Millisecond)
+
+		s.workers.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				s.workers.Done()
+				wakeAIWorkers()
+			}()
+			s.runJob(job)
+		}()
 	}
 }
 
-// processPaste processes a single paste for AI title generation
+// runJob executes a claimed job and records its outcome
+func (s *AIProcessorService) runJob(job *models.Job) {
+	var err error
+	switch job.Type {
+	case models.JobTypeClassifyPaste:
+		err = s.classifyPaste(job.PasteID)
+	default:
+		err = fmt.Errorf("unknown job type %q", job.Type)
+	}
+
+	if err == nil {
+		if err := database.CompleteJob(job.ID); err != nil {
+			log.Printf("Error completing AI job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	// The job could not run at all, it waits in the queue until AI is enabled again
+	if errors.Is(err, errAIDisabled) {
+		if err := database.RequeueJob(job.ID); err != nil {
+			log.Printf("Error requeueing AI job %d: %v", job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Error processing paste %d (attempt %d/%d): %v", job.PasteID, job.Attempts, job.MaxAttempts, err)
+	database.IncrementPasteRetryCount(job.PasteID)
+
+	var retryAt *time.Time
+	if job.Attempts < job.MaxAttempts {
+		next := time.Now().Add(retryDelay(job.Attempts))
+		retryAt = &next
+	}
+	if err := database.FailJob(job.ID, err, retryAt); err != nil {
+		log.Printf("Error recording failure of AI job %d: %v", job.ID, err)
+	}
+}
+
+// retryDelay returns the exponential backoff after the given number of failed attempts
+func retryDelay(attempts int) time.Duration {
+	delay := aiRetryBaseDelay
+	for i := 1; i < attempts && delay < aiRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, aiRetryMaxDelay)
+}
+
+// classifyPaste loads a paste and classifies it, pastes deleted in the meantime are skipped
+func (s *AIProcessorService) classifyPaste(pasteID int) error {
+	paste, err := database.GetPasteForAIProcessing(pasteID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if paste.AIClassified {
+		return nil
+	}
+	return s.processPaste(paste)
+}
+
+// aiWorkers returns the configured number of concurrent workers
+func aiWorkers() int {
+	return intConfig("ai_workers", defaultAIWorkers, 1, maxAIWorkers)
+}
+
+// aiMaxAttempts returns the configured number of attempts per job
+func aiMaxAttempts() int {
+	return intConfig("ai_max_attempts", defaultAIMaxAttempts, 1, 100)
+}
+
+// intConfig reads an integer configuration value, falling back when it is missing or out of range
+func intConfig(key string, fallback, minValue, maxValue int) int {
+	config, err := database.GetConfigByKey(key)
+	if err != nil {
+		return fallback
+	}
+	value, err := strconv.Atoi(config.Value)
+	if err != nil || value < minValue || value > maxValue {
+		return fallback
+	}
+	return value
+}
+
+// processPaste asks the AI for a title, description, tags and language of a single paste
 func (s *AIProcessorService) processPaste(paste *models.Paste) error {
-	// Check if AI is enabled
-	aiEnabledConfig, err := database.GetConfigByKey("ai_enabled")
-	if err != nil || aiEnabledConfig.Value != "true" {
-		// AI is not enabled, mark as processed to avoid retrying
-		return database.UpdatePasteAIStatus(paste.ID, paste.Title, true, paste.AIRetryCount)
+	// Never send ciphertext of encrypted pastes to the AI, mark them as processed
+	if !paste.CanInspectContent() {
+		return database.MarkPasteClassified(paste.ID)
+	}
+	// A password was set after the paste was queued, its content must not leak through a title.
+	// The paste is queued again when the password is removed.
+	if paste.PasswordHash != "" {
+		return nil
+	}
+	// Secrets may have been added by an edit after the paste was queued
+	keepsSecrets, err := database.PasteKeepsSecrets(paste.ID)
+	if err != nil {
+		return err
+	}
+	if keepsSecrets {
+		return nil
 	}
 
-	// Generate title using AI
 	request := GenerateTitleRequest{
+		Title:   paste.Title,
 		Content: paste.Content,
 		Created: paste.CreatedAt.Format("2006-01-02 15:04"),
+		Source:  models.AIUsageSourceClassify,
+		PasteID: paste.ID,
 	}
-	
+
 	response, err := s.aiService.GenerateTitle(request)
 	if err != nil {
 		return fmt.Errorf("failed to generate title: %v", err)
 	}
-	
-	// Use the generated title, fallback to original title if AI returns nil
-	title := paste.Title
-	if response != nil && response.Title != "" {
-		title = response.Title
+	if response == nil {
+		// AI was disabled in the meantime, the job is retried later
+		return errAIDisabled
 	}
 
-	// Update paste with generated title
-	err = database.UpdatePasteAIStatus(paste.ID, title, true, paste.AIRetryCount)
+	classification := newPasteClassification(response)
+	err = database.SavePasteClassification(paste.ID, classification)
 	if err != nil {
 		return fmt.Errorf("failed to update paste: %v", err)
 	}
 
-	log.Printf("Successfully generated title for paste %d: %s", paste.ID, title)
+	log.Printf("Successfully classified paste %d: %s [%s] %v", paste.ID, classification.Title, classification.Language, classification.Tags)
 	return nil
 }
+
+// newPasteClassification turns the AI answer into a classification. Titles, languages and tags
+// chosen by the user are kept when it is saved, the AI only fills in a missing title, replaces
+// a language the detector could not identify and adds tags.
+func newPasteClassification(response *GenerateTitleResponse) models.PasteClassification {
+	classification := models.PasteClassification{
+		Title:       response.Title,
+		Description: response.Desc,
+	}
+
+	if language, ok := models.NormalizeLanguage(response.Language); ok {
+		classification.Language = language
+	}
+
+	for _, tag := range response.Tags {
+		// Models like to answer with phrases, turn them into a single tag
+		classification.Tags = append(classification.Tags, strings.Join(strings.Fields(tag), "-"))
+	}
+	return classification
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"pastebin/models"
+)
+
+func TestNewPasteClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		response GenerateTitleResponse
+		want     models.PasteClassification
+	}{
+		{
+			name:     "copies title and description",
+			response: GenerateTitleResponse{Title: "Nginx 配置", Desc: "反向代理", Language: "nginx"},
+			want:     models.PasteClassification{Title: "Nginx 配置", Description: "反向代理"},
+		},
+		{
+			name:     "normalizes the language",
+			response: GenerateTitleResponse{Language: " Golang "},
+			want:     models.PasteClassification{Language: "go"},
+		},
+		{
+			name:     "turns tag phrases into single tags",
+			response: GenerateTitleResponse{Tags: []string{"web server", " reverse  proxy ", "go"}},
+			want:     models.PasteClassification{Tags: []string{"web-server", "reverse-proxy", "go"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPasteClassification(&tt.response)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newPasteClassification() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Supported values of the ai_provider configuration
+const (
+	AIProviderOpenAI    = "openai"    // OpenAI 及兼容 /chat/completions 的接口
+	AIProviderAnthropic = "anthropic" // Anthropic 风格的 /messages 接口
+	AIProviderOllama    = "ollama"    // 本地 Ollama 风格的 /api/chat 接口
+)
+
+// aiRequestTimeout bounds every request sent to a provider
+const aiRequestTimeout = 30 * time.Second
+
+// errAIConfigIncomplete is returned when the provider is missing a required setting
+var errAIConfigIncomplete = errors.New("AI configuration not complete")
+
+// AIProvider is a model backend that answers prompts
+type AIProvider interface {
+	// Name returns the provider's ai_provider value
+	Name() string
+	// Generate answers a prompt made of a system and a user message
+	Generate(ctx context.Context, request AIGenerateRequest) (*AIGenerateResult, error)
+	// ListModels returns the models the backend offers
+	ListModels(ctx context.Context) ([]Model, error)
+	// HealthCheck verifies the backend is reachable and accepts the credentials
+	HealthCheck(ctx context.Context) error
+}
+
+// AIProviderConfig holds the connection settings shared by all providers
+type AIProviderConfig struct {
+	BaseURL string
+	APIKey  string
+}
+
+// AIGenerateRequest is a single prompt sent to a provider
+type AIGenerateRequest struct {
+	Model       string
+	System      string
+	User        string
+	MaxTokens   int
+	Temperature float64
+}
+
+// AIGenerateResult is a provider's answer together with its token usage
+type AIGenerateResult struct {
+	Text             string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// chatMessage is a single turn of a chat style request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// IsSupportedAIProvider reports whether a provider name is known
+func IsSupportedAIProvider(name string) bool {
+	switch name {
+	case AIProviderOpenAI, AIProviderAnthropic, AIProviderOllama:
+		return true
+	}
+	return false
+}
+
+// NewAIProvider creates the provider with the given name. An empty base URL selects
+// the provider's public default endpoint.
+func NewAIProvider(name string, config AIProviderConfig) (AIProvider, error) {
+	config.BaseURL = strings.TrimRight(strings.TrimSpace(config.BaseURL), "/")
+
+	switch name {
+	case AIProviderOpenAI, "":
+		if config.APIKey == "" {
+			return nil, errAIConfigIncomplete
+		}
+		return newOpenAIProvider(config), nil
+	case AIProviderAnthropic:
+		if config.APIKey == "" {
+			return nil, errAIConfigIncomplete
+		}
+		return newAnthropicProvider(config), nil
+	case AIProviderOllama:
+		// A local Ollama needs no key, one is only sent when configured, e.g. for a proxy
+		return newOllamaProvider(config), nil
+	}
+	return nil, fmt.Errorf("unsupported AI provider %q", name)
+}
+
+// doJSON sends a request with an optional JSON body and decodes a JSON response into out
+func doJSON(ctx context.Context, method, url string, headers map[string]string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: aiRequestTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %v", err)
+	}
+	return nil
+}
+
+// unixTime converts a timestamp to Unix seconds, a missing timestamp becomes 0
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
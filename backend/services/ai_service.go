@@ -3,17 +3,16 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"log"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 
 	"pastebin/database"
-
-	"github.com/openai/openai-go/v2"
-	"github.com/openai/openai-go/v2/option"
+	"pastebin/models"
 )
 
 // AIService handles AI-related operations
@@ -32,15 +31,13 @@ type Model struct {
 	OwnedBy string `json:"owned_by"`
 }
 
-// ModelsResponse represents the response from models API
-type ModelsResponse struct {
-	Object string  `json:"object"`
-	Data   []Model `json:"data"`
-}
+// Provider creates the provider selected by the ai_provider configuration
+func (s *AIService) Provider() (AIProvider, error) {
+	providerConfig, err := database.GetConfigByKey("ai_provider")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider config: %v", err)
+	}
 
-// GetModels retrieves available models from the AI API
-func (s *AIService) GetModels() ([]Model, error) {
-	// Get AI configuration
 	baseURLConfig, err := database.GetConfigByKey("ai_base_url")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get base URL config: %v", err)
@@ -51,78 +48,102 @@ func (s *AIService) GetModels() ([]Model, error) {
 		return nil, fmt.Errorf("failed to get API key config: %v", err)
 	}
 
-	if baseURLConfig.Value == "" || apiKeyConfig.Value == "" {
-		return nil, fmt.Errorf("AI configuration not complete")
-	}
-
-	// Prepare request URL
-	baseURL := strings.TrimRight(baseURLConfig.Value, "/")
-	url := baseURL + "/models"
+	return NewAIProvider(providerConfig.Value, AIProviderConfig{
+		BaseURL: baseURLConfig.Value,
+		APIKey:  apiKeyConfig.Value,
+	})
+}
 
-	// Create request
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+// Enabled reports whether AI generation is switched on and configured
+func (s *AIService) Enabled() bool {
+	enabledConfig, err := database.GetConfigByKey("ai_enabled")
+	if err != nil || enabledConfig.Value != "true" {
+		return false
 	}
+	_, err = s.Provider()
+	return err == nil
+}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKeyConfig.Value)
-
-	// Send request
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+// GetModels retrieves available models from the configured provider
+func (s *AIService) GetModels() ([]Model, error) {
+	provider, err := s.Provider()
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, err
 	}
 
-	// Parse response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), aiRequestTimeout)
+	defer cancel()
+	return provider.ListModels(ctx)
+}
 
-	var modelsResp ModelsResponse
-	err = json.Unmarshal(body, &modelsResp)
+// HealthCheck verifies the configured provider is reachable
+func (s *AIService) HealthCheck() error {
+	provider, err := s.Provider()
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+		return err
 	}
 
-	return modelsResp.Data, nil
+	ctx, cancel := context.WithTimeout(context.Background(), aiRequestTimeout)
+	defer cancel()
+	return provider.HealthCheck(ctx)
 }
 
 // GenerateTitleRequest represents the request format for title generation
 type GenerateTitleRequest struct {
+	Title   string `json:"title,omitempty"` // 已有的标题，供模型参考
 	Content string `json:"content"`
 	Created string `json:"created"`
+
+	Source  string `json:"-"` // 调用来源，记录到用量表
+	PasteID int    `json:"-"` // 后台任务处理的代码片段
 }
 
 // GenerateTitleResponse represents the response format for title generation
 type GenerateTitleResponse struct {
-	Title string `json:"title"`
-	Desc  string `json:"desc"`
+	Title    string   `json:"title"`
+	Desc     string   `json:"desc"`
+	Tags     []string `json:"tags"`
+	Language string   `json:"language"`
 }
 
-// GenerateTitle generates a title and description for the given content using AI
-func (s *AIService) GenerateTitle(request GenerateTitleRequest) (*GenerateTitleResponse, error) {
-	// Get AI configuration
+// Limits applied to the generated classification
+const (
+	maxGeneratedTitleLength = 100
+	maxGeneratedDescLength  = 200
+	maxGeneratedTags        = 5
+	// minClassificationTokens keeps the JSON answer from being cut off by a small ai_max_tokens
+	minClassificationTokens = 200
+	// maxUsageErrorLength keeps error bodies returned by the API from bloating the usage table
+	maxUsageErrorLength = 500
+)
+
+// classificationFormat is appended to the configured prompt so the answer can be parsed
+var classificationFormat = fmt.Sprintf(`Answer with a single JSON object and nothing else:
+{"title": "concise title", "desc": "one sentence description", "tags": ["at most %d short lowercase topic tags"], "language": "one of: %s"}`,
+	maxGeneratedTags, strings.Join(models.LanguageIDs(), ", "))
+
+// aiSettings is the AI configuration shared by every kind of request
+type aiSettings struct {
+	provider    AIProvider
+	model       string
+	prompt      string
+	maxTokens   int
+	temperature float64
+}
+
+// loadSettings reads the AI configuration. It returns nil when AI is disabled
+// or the provider is missing its API key.
+func (s *AIService) loadSettings() (*aiSettings, error) {
 	enabledConfig, err := database.GetConfigByKey("ai_enabled")
 	if err != nil || enabledConfig.Value != "true" {
 		return nil, nil // AI is disabled, return nil
 	}
 
-	baseURLConfig, err := database.GetConfigByKey("ai_base_url")
-	if err != nil {
-		return nil, err
+	// Skip if the provider is missing its API key
+	provider, err := s.Provider()
+	if errors.Is(err, errAIConfigIncomplete) {
+		return nil, nil
 	}
-
-	apiKeyConfig, err := database.GetConfigByKey("ai_api_key")
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +171,7 @@ func (s *AIService) GenerateTitle(request GenerateTitleRequest) (*GenerateTitleR
 	// Parse configuration values
 	maxTokens, err := strconv.Atoi(maxTokensConfig.Value)
 	if err != nil {
-		maxTokens = 100 // default value increased for JSON response
+		maxTokens = 0
 	}
 
 	temperature, err := strconv.ParseFloat(temperatureConfig.Value, 64)
@@ -158,9 +179,54 @@ func (s *AIService) GenerateTitle(request GenerateTitleRequest) (*GenerateTitleR
 		temperature = 0.7 // default value
 	}
 
-	// Skip if API key is empty
-	if apiKeyConfig.Value == "" {
-		return nil, nil
+	return &aiSettings{
+		provider:    provider,
+		model:       modelConfig.Value,
+		prompt:      promptConfig.Value,
+		maxTokens:   maxTokens,
+		temperature: temperature,
+	}, nil
+}
+
+// complete sends a prompt to the provider, hands the answer to parse and records the call
+// in the usage table. minTokens raises a configured ai_max_tokens too small for the answer.
+func (s *AIService) complete(settings *aiSettings, usage *models.AIUsage, system, user string, minTokens int, parse func(text string) error) error {
+	// Create context with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), aiRequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := settings.provider.Generate(ctx, AIGenerateRequest{
+		Model:       settings.model,
+		System:      system,
+		User:        user,
+		MaxTokens:   max(settings.maxTokens, minTokens),
+		Temperature: settings.temperature,
+	})
+
+	usage.Provider = settings.provider.Name()
+	usage.Model = settings.model
+	usage.LatencyMs = time.Since(start).Milliseconds()
+	if result != nil {
+		usage.PromptTokens = result.PromptTokens
+		usage.CompletionTokens = result.CompletionTokens
+		if result.Model != "" {
+			usage.Model = result.Model
+		}
+	}
+
+	if err == nil {
+		err = parse(result.Text)
+	}
+	recordUsage(usage, err)
+	return err
+}
+
+// GenerateTitle generates a title, description, tags and language for the given content using AI
+func (s *AIService) GenerateTitle(request GenerateTitleRequest) (*GenerateTitleResponse, error) {
+	settings, err := s.loadSettings()
+	if settings == nil || err != nil {
+		return nil, err
 	}
 
 	// Prepare user input JSON
@@ -169,59 +235,93 @@ func (s *AIService) GenerateTitle(request GenerateTitleRequest) (*GenerateTitleR
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	// Create OpenAI client with custom base URL if provided
-	var client openai.Client
-	if baseURLConfig.Value != "" && baseURLConfig.Value != "https://api.openai.com/v1" {
-		client = openai.NewClient(
-			option.WithAPIKey(apiKeyConfig.Value),
-			option.WithBaseURL(strings.TrimRight(baseURLConfig.Value, "/")),
-		)
-	} else {
-		client = openai.NewClient(
-			option.WithAPIKey(apiKeyConfig.Value),
-		)
+	var response *GenerateTitleResponse
+	usage := &models.AIUsage{Source: request.Source, PasteID: request.PasteID}
+	err = s.complete(settings, usage, settings.prompt+"\n\n"+classificationFormat, string(userInput), minClassificationTokens,
+		func(text string) error {
+			var err error
+			response, err = parseClassification(text)
+			return err
+		})
+	if err != nil {
+		return nil, err
 	}
+	return response, nil
+}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// parseClassification parses the JSON answer of the model and applies the length limits
+func parseClassification(text string) (*GenerateTitleResponse, error) {
+	responseContent := stripCodeFence(strings.TrimSpace(text))
+	if responseContent == "" {
+		return nil, fmt.Errorf("no response from AI")
+	}
 
-	// Make the API call with system and user messages
-	chatCompletion, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(promptConfig.Value),
-			openai.UserMessage(string(userInput)),
-		},
-		Model:       openai.ChatModel(modelConfig.Value),
-		MaxTokens:   openai.Int(int64(maxTokens)),
-		Temperature: openai.Float(temperature),
-		ReasoningEffort: openai.ReasoningEffortLow,
-	})
+	// Try to parse JSON response
+	var response GenerateTitleResponse
+	err := json.Unmarshal([]byte(responseContent), &response)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call OpenAI API: %v", err)
+		return nil, fmt.Errorf("failed to parse AI response as JSON: %v", err)
 	}
 
-	// Extract response from AI
-	if len(chatCompletion.Choices) > 0 && chatCompletion.Choices[0].Message.Content != "" {
-		responseContent := strings.TrimSpace(chatCompletion.Choices[0].Message.Content)
+	// Limit title, description and tags
+	response.Title = truncateRunes(strings.TrimSpace(response.Title), maxGeneratedTitleLength)
+	response.Desc = truncateRunes(strings.TrimSpace(response.Desc), maxGeneratedDescLength)
+	if len(response.Tags) > maxGeneratedTags {
+		response.Tags = response.Tags[:maxGeneratedTags]
+	}
 
-		// Try to parse JSON response
-		var response GenerateTitleResponse
-		err := json.Unmarshal([]byte(responseContent), &response)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse AI response as JSON: %v", err)
-		}
+	return &response, nil
+}
 
-		// Limit title and description length
-		if len(response.Title) > 100 {
-			response.Title = response.Title[:100]
-		}
-		if len(response.Desc) > 200 {
-			response.Desc = response.Desc[:200]
-		}
+// recordUsage stores a call in the usage table. Failing to record is only logged,
+// accounting must not break the call itself.
+func recordUsage(usage *models.AIUsage, err error) {
+	usage.Success = err == nil
+	if err != nil {
+		usage.Error = truncateRunes(err.Error(), maxUsageErrorLength)
+	}
+	if err := database.RecordAIUsage(usage); err != nil {
+		log.Printf("Error recording AI usage: %v", err)
+	}
+}
 
-		return &response, nil
+// Budget returns today's usage against the daily budgets set by ai_daily_token_budget
+// and ai_daily_request_budget, where 0 means unlimited
+func (s *AIService) Budget() (*models.AIBudget, error) {
+	requests, tokens, err := database.GetAIUsageToday()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("no response from AI")
+	budget := &models.AIBudget{
+		TokenLimit:   int64(intConfig("ai_daily_token_budget", 0, 0, math.MaxInt32)),
+		RequestLimit: int64(intConfig("ai_daily_request_budget", 0, 0, math.MaxInt32)),
+		TokensUsed:   tokens,
+		RequestsUsed: requests,
+	}
+	budget.Exceeded = (budget.TokenLimit > 0 && tokens >= budget.TokenLimit) ||
+		(budget.RequestLimit > 0 && requests >= budget.RequestLimit)
+	return budget, nil
+}
+
+// stripCodeFence removes the Markdown code fence some models wrap JSON answers in
+func stripCodeFence(text string) string {
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+		// Drop the info string, e.g. ```json
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// truncateRunes shortens text to at most limit characters without splitting one
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit])
 }
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAnthropicBaseURL is the official Anthropic endpoint
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	// anthropicVersion is the API version sent with every request
+	anthropicVersion = "2023-06-01"
+)
+
+// anthropicProvider talks to Anthropic style messages APIs
+type anthropicProvider struct {
+	config AIProviderConfig
+}
+
+// newAnthropicProvider creates an Anthropic style provider
+func newAnthropicProvider(config AIProviderConfig) *anthropicProvider {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultAnthropicBaseURL
+	}
+	return &anthropicProvider{config: config}
+}
+
+// chatMessagesRequest is the body of a /messages request
+type chatMessagesRequest struct {
+	Model       string        `json:"model"`
+	System      string        `json:"system,omitempty"`
+	Messages    []chatMessage `json:"messages"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature float64       `json:"temperature"`
+}
+
+// chatMessagesResponse is the part of a /messages response we use
+type chatMessagesResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicModelsResponse is the response of the /models endpoint
+type anthropicModelsResponse struct {
+	Data []struct {
+		ID        string    `json:"id"`
+		Type      string    `json:"type"`
+		CreatedAt time.Time `json:"created_at"`
+	} `json:"data"`
+}
+
+// headers returns the authentication headers of every request
+func (p *anthropicProvider) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         p.config.APIKey,
+		"anthropic-version": anthropicVersion,
+	}
+}
+
+// Name returns the provider's ai_provider value
+func (p *anthropicProvider) Name() string {
+	return AIProviderAnthropic
+}
+
+// Generate answers a prompt through the messages API
+func (p *anthropicProvider) Generate(ctx context.Context, request AIGenerateRequest) (*AIGenerateResult, error) {
+	body := chatMessagesRequest{
+		Model:       request.Model,
+		System:      request.System,
+		Messages:    []chatMessage{{Role: "user", Content: request.User}},
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+	}
+
+	var response chatMessagesResponse
+	if err := doJSON(ctx, "POST", p.config.BaseURL+"/messages", p.headers(), body, &response); err != nil {
+		return nil, fmt.Errorf("failed to call Anthropic API: %v", err)
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	return &AIGenerateResult{
+		Text:             text.String(),
+		Model:            response.Model,
+		PromptTokens:     response.Usage.InputTokens,
+		CompletionTokens: response.Usage.OutputTokens,
+	}, nil
+}
+
+// ListModels retrieves the models from the /models endpoint
+func (p *anthropicProvider) ListModels(ctx context.Context) ([]Model, error) {
+	var response anthropicModelsResponse
+	if err := doJSON(ctx, "GET", p.config.BaseURL+"/models", p.headers(), nil, &response); err != nil {
+		return nil, err
+	}
+
+	models := make([]Model, 0, len(response.Data))
+	for _, model := range response.Data {
+		models = append(models, Model{
+			ID:      model.ID,
+			Object:  model.Type,
+			Created: unixTime(model.CreatedAt),
+			OwnedBy: AIProviderAnthropic,
+		})
+	}
+	return models, nil
+}
+
+// HealthCheck lists the models, which needs a reachable API and a valid key
+func (p *anthropicProvider) HealthCheck(ctx context.Context) error {
+	_, err := p.ListModels(ctx)
+	return err
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptLimiter limits the failed attempts per key in a fixed time window
+type AttemptLimiter struct {
+	maxAttempts int
+	window      time.Duration
+	mutex       sync.Mutex
+	attempts    map[string]*attemptWindow
+}
+
+// attemptWindow holds the failures recorded for a key since the window started
+type attemptWindow struct {
+	count   int
+	started time.Time
+}
+
+// NewAttemptLimiter creates a limiter allowing maxAttempts failures per key within window
+func NewAttemptLimiter(maxAttempts int, window time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		maxAttempts: maxAttempts,
+		window:      window,
+		attempts:    make(map[string]*attemptWindow),
+	}
+}
+
+// Reserve counts an attempt for the key and reports whether it may be made. The attempt
+// is counted before it is checked, so concurrent attempts cannot all pass the limit
+// together. Release gives the attempt back when it succeeds.
+func (l *AttemptLimiter) Reserve(key string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	entry, ok := l.attempts[key]
+	if !ok || now.Sub(entry.started) >= l.window {
+		l.prune(now)
+		l.attempts[key] = &attemptWindow{count: 1, started: now}
+		return true
+	}
+	if entry.count >= l.maxAttempts {
+		return false
+	}
+	entry.count++
+	return true
+}
+
+// Release gives back an attempt reserved for the key that turned out to succeed
+func (l *AttemptLimiter) Release(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if entry, ok := l.attempts[key]; ok && entry.count > 0 {
+		entry.count--
+	}
+}
+
+// prune drops windows that have already ended so the map does not grow without bound
+func (l *AttemptLimiter) prune(now time.Time) {
+	for key, entry := range l.attempts {
+		if now.Sub(entry.started) >= l.window {
+			delete(l.attempts, key)
+		}
+	}
+}
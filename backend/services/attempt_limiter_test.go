@@ -0,0 +1,108 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	// 每一步：对 key 预留一次尝试，期望是否允许，允许后是否成功 (成功则归还)
+	type step struct {
+		key     string
+		allowed bool
+		succeed bool
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "locks a key after the maximum failures",
+			steps: []step{
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", false, false},
+				{"1.2.3.4 paste", false, true},
+			},
+		},
+		{
+			name: "a locked client does not lock out other clients of the paste",
+			steps: []step{
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", false, false},
+				{"5.6.7.8 paste", true, true},
+				{"1.2.3.4 other", true, false},
+			},
+		},
+		{
+			name: "successful attempts are not counted",
+			steps: []step{
+				{"1.2.3.4 paste", true, true},
+				{"1.2.3.4 paste", true, true},
+				{"1.2.3.4 paste", true, true},
+				{"1.2.3.4 paste", true, true},
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", true, false},
+				{"1.2.3.4 paste", false, false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewAttemptLimiter(3, time.Hour)
+			for i, step := range tt.steps {
+				allowed := limiter.Reserve(step.key)
+				if allowed != step.allowed {
+					t.Fatalf("step %d: Reserve(%q) = %v, want %v", i, step.key, allowed, step.allowed)
+				}
+				if allowed && step.succeed {
+					limiter.Release(step.key)
+				}
+			}
+		})
+	}
+}
+
+func TestAttemptLimiterWindowExpires(t *testing.T) {
+	limiter := NewAttemptLimiter(1, 20*time.Millisecond)
+	if !limiter.Reserve("key") {
+		t.Fatal("first attempt was refused")
+	}
+	if limiter.Reserve("key") {
+		t.Fatal("second attempt in the window was allowed")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !limiter.Reserve("key") {
+		t.Fatal("attempt after the window was refused")
+	}
+}
+
+func TestAttemptLimiterConcurrentAttempts(t *testing.T) {
+	limiter := NewAttemptLimiter(5, time.Hour)
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Reserve("key") {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 5 {
+		t.Errorf("%d concurrent attempts were allowed, want 5", got)
+	}
+}
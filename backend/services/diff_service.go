@@ -0,0 +1,293 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"pastebin/models"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change
+	diffContextLines = 3
+	// maxDiffEditDistance bounds the work done by the Myers search, beyond it
+	// the two texts are reported as a full replacement
+	maxDiffEditDistance = 2000
+)
+
+// DiffService computes line based diffs between texts
+type DiffService struct{}
+
+// NewDiffService creates a new diff service instance
+func NewDiffService() *DiffService {
+	return &DiffService{}
+}
+
+// diffOp is a single step of an edit script
+type diffOp struct {
+	kind    string // context, add 或 delete
+	content string
+	oldPos  int // 0-based index into the old lines before this op
+	newPos  int // 0-based index into the new lines before this op
+}
+
+// Diff computes the unified diff hunks turning oldText into newText
+func (s *DiffService) Diff(oldText, newText string) []models.DiffHunk {
+	oldLines := splitLines(oldText)
+	newLines := splitLines(newText)
+
+	ops := s.editScript(oldLines, newLines)
+	return buildHunks(ops, diffContextLines)
+}
+
+// FormatUnified renders a diff result in the unified diff text format
+func (s *DiffService) FormatUnified(result models.DiffResult) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s@%d\n", result.A.RandomID, result.A.Revision)
+	fmt.Fprintf(&sb, "+++ b/%s@%d\n", result.B.RandomID, result.B.Revision)
+
+	for _, hunk := range result.Hunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Type {
+			case "add":
+				sb.WriteString("+")
+			case "delete":
+				sb.WriteString("-")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(line.Content)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// hunkRange formats a hunk header range, omitting the length when it is 1
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// splitLines splits text into lines, ignoring a single trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// editScript returns the shortest edit script between a and b using Myers' algorithm.
+// Common prefix and suffix lines are stripped first since they are the usual case for edits.
+func (s *DiffService) editScript(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: "context", content: a[i], oldPos: i, newPos: i})
+	}
+
+	middle := myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, op := range middle {
+		op.oldPos += prefix
+		op.newPos += prefix
+		ops = append(ops, op)
+	}
+
+	for i := 0; i < suffix; i++ {
+		oldPos := len(a) - suffix + i
+		newPos := len(b) - suffix + i
+		ops = append(ops, diffOp{kind: "context", content: a[oldPos], oldPos: oldPos, newPos: newPos})
+	}
+
+	return ops
+}
+
+// myersDiff runs the greedy Myers search and backtracks through the recorded frontiers
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	maxD := n + m
+	if maxD > maxDiffEditDistance {
+		maxD = maxDiffEditDistance
+	}
+
+	// v[k+offset] holds the furthest x reached on diagonal k
+	offset := maxD + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	for d := 0; d <= maxD; d++ {
+		// Only diagonals -d..d are reachable at step d, keep just those
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	// Too many differences, report a full replacement
+	var ops []diffOp
+	for i, line := range a {
+		ops = append(ops, diffOp{kind: "delete", content: line, oldPos: i, newPos: 0})
+	}
+	for j, line := range b {
+		ops = append(ops, diffOp{kind: "add", content: line, oldPos: n, newPos: j})
+	}
+	return ops
+}
+
+// backtrack walks the recorded frontiers from the end to rebuild the edit script
+func backtrack(a, b []string, trace [][]int) []diffOp {
+	x, y := len(a), len(b)
+	var reversed []diffOp
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d] covers diagonals -d..d
+		frontier := func(k int) int {
+			if k < -d || k > d {
+				return 0
+			}
+			return trace[d][k+d]
+		}
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && frontier(k-1) < frontier(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := frontier(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, diffOp{kind: "context", content: a[x], oldPos: x, newPos: y})
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffOp{kind: "add", content: b[prevY], oldPos: x, newPos: prevY})
+			} else {
+				reversed = append(reversed, diffOp{kind: "delete", content: a[prevX], oldPos: prevX, newPos: y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// buildHunks groups changed ops with the given amount of surrounding context
+func buildHunks(ops []diffOp, context int) []models.DiffHunk {
+	var hunks []models.DiffHunk
+
+	i := 0
+	for i < len(ops) {
+		// Find the next change
+		for i < len(ops) && ops[i].kind == "context" {
+			i++
+		}
+		if i >= len(ops) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		end := i
+		for end < len(ops) {
+			next := end
+			for next < len(ops) && ops[next].kind != "context" {
+				next++
+			}
+			gap := next
+			for gap < len(ops) && ops[gap].kind == "context" {
+				gap++
+			}
+			if gap < len(ops) && gap-next <= 2*context {
+				end = gap
+				continue
+			}
+			end = next + context
+			if end > len(ops) {
+				end = len(ops)
+			}
+			break
+		}
+
+		hunk := models.DiffHunk{
+			OldStart: ops[start].oldPos,
+			NewStart: ops[start].newPos,
+		}
+		for _, op := range ops[start:end] {
+			line := models.DiffLine{Type: op.kind, Content: op.content}
+			switch op.kind {
+			case "context":
+				hunk.OldLines++
+				hunk.NewLines++
+				line.OldLine = op.oldPos + 1
+				line.NewLine = op.newPos + 1
+			case "delete":
+				hunk.OldLines++
+				line.OldLine = op.oldPos + 1
+			case "add":
+				hunk.NewLines++
+				line.NewLine = op.newPos + 1
+			}
+			hunk.Lines = append(hunk.Lines, line)
+		}
+
+		// Unified diff ranges are 1-based, an empty range points at the line before it
+		if hunk.OldLines > 0 {
+			hunk.OldStart++
+		}
+		if hunk.NewLines > 0 {
+			hunk.NewStart++
+		}
+
+		hunks = append(hunks, hunk)
+		i = end
+	}
+
+	return hunks
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pastebin/database"
+	"pastebin/models"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+const (
+	// defaultHighlightTheme is used when neither the request nor the configuration names a known theme
+	defaultHighlightTheme = "github"
+	// lineAnchorPrefix prefixes the line anchors, giving links such as #L10
+	lineAnchorPrefix = "L"
+)
+
+// HighlightOptions controls how a paste is rendered
+type HighlightOptions struct {
+	Theme          string   // chroma 主题名，为空时使用配置的默认主题
+	LineNumbers    bool     // 是否显示行号
+	HighlightLines [][2]int // 需要突出显示的行范围，闭区间
+}
+
+// HighlightResult is a rendered paste ready to be embedded in a page
+type HighlightResult struct {
+	HTML     string `json:"html"`
+	CSS      string `json:"css"`
+	Theme    string `json:"theme"`
+	Language string `json:"language"`
+}
+
+// HighlightService renders syntax highlighted HTML with chroma
+type HighlightService struct{}
+
+// NewHighlightService creates a new highlight service instance
+func NewHighlightService() *HighlightService {
+	return &HighlightService{}
+}
+
+// Highlight tokenizes the content in the given language and renders it as HTML.
+// Every line gets an anchor so links like #L10 point at it.
+func (s *HighlightService) Highlight(content, language string, options HighlightOptions) (*HighlightResult, error) {
+	lexer := lexers.Get(language)
+	if lexer == nil || language == models.LanguagePlaintext {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	theme := s.ResolveTheme(options.Theme)
+	style := styles.Get(theme)
+
+	formatter := html.New(
+		html.WithClasses(true),
+		html.WithLineNumbers(options.LineNumbers),
+		html.WithLinkableLineNumbers(options.LineNumbers, lineAnchorPrefix),
+		html.HighlightLines(options.HighlightLines),
+		html.TabWidth(4),
+	)
+
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to tokenize paste: %v", err)
+	}
+
+	var body strings.Builder
+	if err := formatter.Format(&body, style, iterator); err != nil {
+		return nil, fmt.Errorf("failed to render paste: %v", err)
+	}
+	css, err := s.ThemeCSS(theme)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HighlightResult{
+		HTML:     body.String(),
+		CSS:      css,
+		Theme:    theme,
+		Language: language,
+	}, nil
+}
+
+// ThemeCSS returns the stylesheet for the classes emitted when highlighting with the theme
+func (s *HighlightService) ThemeCSS(theme string) (string, error) {
+	var css strings.Builder
+	if err := html.New(html.WithClasses(true)).WriteCSS(&css, styles.Get(theme)); err != nil {
+		return "", fmt.Errorf("failed to render theme: %v", err)
+	}
+	return css.String(), nil
+}
+
+// ResolveTheme returns the requested theme if it exists, otherwise the configured default
+func (s *HighlightService) ResolveTheme(name string) string {
+	if name != "" && isKnownTheme(name) {
+		return name
+	}
+	if config, err := database.GetConfigByKey("paste_highlight_theme"); err == nil && isKnownTheme(config.Value) {
+		return config.Value
+	}
+	return defaultHighlightTheme
+}
+
+// isKnownTheme reports whether chroma ships a style with the given name
+func isKnownTheme(name string) bool {
+	_, ok := styles.Registry[name]
+	return ok
+}
+
+// ParseLineRanges parses a line selection such as "10-20,30" into inclusive ranges
+func ParseLineRanges(spec string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(strings.ReplaceAll(part, "L", ""))
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil || start <= 0 {
+			return nil, fmt.Errorf("invalid line range %q", part)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.Atoi(bounds[1])
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid line range %q", part)
+			}
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+
+	// The formatter walks the ranges in order
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	return ranges, nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import "sync"
+
+// keyedMutex holds a separate lock per key, locks are dropped once nobody holds or waits for them
+type keyedMutex struct {
+	mutex sync.Mutex
+	locks map[string]*keyedLock
+}
+
+// keyedLock is the lock of one key and how many callers hold or wait for it
+type keyedLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+// newKeyedMutex creates an empty set of per key locks
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*keyedLock)}
+}
+
+// Lock blocks until the key's lock is acquired and returns the function releasing it
+func (m *keyedMutex) Lock(key string) func() {
+	m.mutex.Lock()
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		m.locks[key] = lock
+	}
+	lock.refs++
+	m.mutex.Unlock()
+
+	lock.mutex.Lock()
+	return func() {
+		lock.mutex.Unlock()
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(m.locks, key)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"pastebin/models"
+)
+
+const (
+	// maxDetectionBytes bounds how much of a paste is scanned for keywords
+	maxDetectionBytes = 64 * 1024
+	// minDetectionScore is the score a language needs before it is picked over plain text
+	minDetectionScore = 6
+	// maxSignalMatches caps how often a single signal counts towards a score
+	maxSignalMatches = 5
+)
+
+// languageSignal is a pattern hinting at a language, weighted by how specific it is
+type languageSignal struct {
+	pattern *regexp.Regexp
+	weight  int
+}
+
+// signal compiles a multi-line pattern for the signal tables
+func signal(pattern string, weight int) languageSignal {
+	return languageSignal{pattern: regexp.MustCompile("(?m)" + pattern), weight: weight}
+}
+
+// languageSignals are the keyword patterns scored for each language
+var languageSignals = map[string][]languageSignal{
+	"go": {
+		signal(`^package \w+$`, 5),
+		signal(`^import \($`, 4),
+		signal(`\bfunc (\(\w+ \*?\w+\) )?\w+\(`, 3),
+		signal(`\berr != nil\b`, 4),
+		signal(`:= `, 1),
+		signal(`\bfmt\.\w+\(`, 3),
+	},
+	"python": {
+		signal(`^\s*def \w+\(.*\):\s*$`, 4),
+		signal(`^from [\w.]+ import `, 4),
+		signal(`^import \w+$`, 1),
+		signal(`\bself\.\w+`, 2),
+		signal(`^\s*elif .*:\s*$`, 4),
+		signal(`__name__ == ['"]__main__['"]`, 5),
+		signal(`\bprint\(`, 1),
+		signal(`^\s*class \w+(\(.*\))?:\s*$`, 4),
+	},
+	"javascript": {
+		signal(`\bconsole\.log\(`, 3),
+		signal(`\bfunction\s*\w*\s*\(`, 1),
+		signal(`\bconst \w+ = `, 1),
+		signal(`\blet \w+ = `, 1),
+		signal(`=> \{`, 2),
+		signal(`\brequire\(['"]`, 3),
+		signal(`\bmodule\.exports\b`, 5),
+		signal(`\bdocument\.\w+`, 3),
+		signal(`\basync function\b`, 2),
+	},
+	"typescript": {
+		signal(`: (string|number|boolean|any|void)\b`, 3),
+		signal(`^\s*(export )?interface \w+`, 3),
+		signal(`^\s*(export )?type \w+ = `, 3),
+		signal(`\bimport .* from ['"]`, 1),
+		signal(`\b(public|private|readonly) \w+: `, 3),
+	},
+	"java": {
+		signal(`\bpublic (static )?(final )?(class|void|interface)\b`, 3),
+		signal(`\bSystem\.out\.print`, 5),
+		signal(`^\s*@Override\b`, 4),
+		signal(`^import java\.`, 6),
+		signal(`\bpublic static void main\(String`, 6),
+	},
+	"c": {
+		signal(`^#include <\w+\.h>`, 4),
+		signal(`\bprintf\(`, 2),
+		signal(`\bint main\(`, 2),
+		signal(`\b(malloc|free|sizeof)\(`, 2),
+		signal(`^#define \w+`, 2),
+	},
+	"cpp": {
+		signal(`^#include <(iostream|vector|string|map|memory|algorithm)>`, 6),
+		signal(`\bstd::`, 4),
+		signal(`\bcout <<`, 3),
+		signal(`\btemplate ?<`, 3),
+		signal(`^using namespace \w+;`, 5),
+	},
+	"csharp": {
+		signal(`^using System(\.\w+)*;`, 6),
+		signal(`\bConsole\.Write(Line)?\(`, 5),
+		signal(`\bpublic (async )?Task\b`, 3),
+		signal(`^namespace [\w.]+`, 2),
+		signal(`\{ get; (private )?set; \}`, 5),
+	},
+	"rust": {
+		signal(`\bfn \w+\(`, 2),
+		signal(`\blet mut \w+`, 4),
+		signal(`\bprintln!\(`, 5),
+		signal(`^use std::`, 6),
+		signal(`^\s*impl\b`, 3),
+		signal(`-> Result<`, 3),
+		signal(`&str\b`, 2),
+	},
+	"ruby": {
+		signal(`^\s*def \w+[?!]?(\(.*\))?\s*$`, 2),
+		signal(`^\s*end$`, 2),
+		signal(`\bputs `, 2),
+		signal(`^require ['"]`, 3),
+		signal(`\.each do \|`, 5),
+		signal(`\battr_(accessor|reader|writer)\b`, 5),
+	},
+	"php": {
+		signal(`<\?php`, 10),
+		signal(`\$\w+ = `, 1),
+		signal(`\bfunction \w+\(\$`, 4),
+		signal(`\becho \$`, 3),
+	},
+	"bash": {
+		signal(`^\s*echo `, 1),
+		signal(`^\s*(if|while) \[\[? `, 4),
+		signal(`^\s*fi$`, 4),
+		signal(`^\s*done$`, 3),
+		signal(`^\s*export \w+=`, 3),
+		signal(`\b(sudo|apt-get|yum|brew) `, 2),
+		signal(`\$\{\w+\}`, 1),
+	},
+	"sql": {
+		signal(`(?i)\bselect\b[^;]+\bfrom\b`, 4),
+		signal(`(?i)\binsert into\b`, 5),
+		signal(`(?i)\bcreate (table|index|view)\b`, 6),
+		signal(`(?i)\bupdate \w+ set\b`, 5),
+		signal(`(?i)\b(inner|left|right) join\b`, 4),
+	},
+	"yaml": {
+		signal(`^[\w-]+:\s*$`, 1),
+		signal(`^[\w-]+: \S`, 1),
+		signal(`^\s+- [\w"']`, 1),
+		signal(`^---$`, 2),
+	},
+	"html": {
+		signal(`(?i)<(div|span|body|head|p|a|ul|li|meta|link|title)[\s>]`, 2),
+		signal(`(?i)</(div|span|body|head|html|p|a|ul|li)>`, 2),
+	},
+	"xml": {
+		signal(`^<\?xml `, 10),
+		signal(`<\w+:\w+[\s>]`, 2),
+	},
+	"css": {
+		signal(`^\s*[.#]?[\w-]+( [.#]?[\w-]+)*\s*\{\s*$`, 1),
+		signal(`^\s*[\w-]+:\s*[^;{]+;\s*$`, 1),
+		signal(`@media\b`, 4),
+		signal(`!important\b`, 3),
+	},
+	"markdown": {
+		signal(`^#{1,6} \S`, 1),
+		signal(`^\s*[-*] \[[ xX]\] `, 4),
+		signal("^```", 3),
+		signal(`\[[^\]]+\]\([^)]+\)`, 2),
+		signal(`^\|.*\|\s*$`, 1),
+		signal(`\*\*[^*]+\*\*`, 1),
+	},
+	"diff": {
+		signal(`^@@ -\d+(,\d+)? \+\d+(,\d+)? @@`, 6),
+		signal(`^(\+\+\+|---) \S`, 3),
+	},
+	"lua": {
+		signal(`^\s*local \w+ = `, 3),
+		signal(`\bfunction \w+[.:]?\w*\(.*\)\s*$`, 1),
+		signal(`^\s*end$`, 1),
+		signal(`\bthen$`, 3),
+	},
+}
+
+// shebangLanguages maps interpreters named in a shebang line to a language
+var shebangLanguages = map[string]string{
+	"bash":    "bash",
+	"sh":      "bash",
+	"zsh":     "bash",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"lua":     "lua",
+	"pwsh":    "powershell",
+}
+
+// LanguageDetector guesses the syntax language of a paste
+type LanguageDetector struct{}
+
+// NewLanguageDetector creates a new language detector instance
+func NewLanguageDetector() *LanguageDetector {
+	return &LanguageDetector{}
+}
+
+// Detect guesses the language from, in order, a file name in the title, a
+// shebang line, unambiguous file headers and finally keyword frequency
+func (d *LanguageDetector) Detect(title, content string) string {
+	if language, ok := models.LanguageForFilename(title); ok {
+		return language
+	}
+
+	if len(content) > maxDetectionBytes {
+		content = content[:maxDetectionBytes]
+	}
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return models.LanguagePlaintext
+	}
+
+	if language, ok := shebangLanguage(trimmed); ok {
+		return language
+	}
+	if language, ok := headerLanguage(trimmed); ok {
+		return language
+	}
+
+	best, bestScore := models.LanguagePlaintext, 0
+	for language, signals := range languageSignals {
+		score := 0
+		for _, s := range signals {
+			matches := len(s.pattern.FindAllStringIndex(content, maxSignalMatches))
+			score += matches * s.weight
+		}
+		// Ties are broken by name so detection is deterministic
+		if score > bestScore || (score == bestScore && score > 0 && language < best) {
+			best, bestScore = language, score
+		}
+	}
+	if bestScore < minDetectionScore {
+		return models.LanguagePlaintext
+	}
+	return best
+}
+
+// shebangLanguage reads the interpreter from a "#!" first line
+func shebangLanguage(content string) (string, bool) {
+	if !strings.HasPrefix(content, "#!") {
+		return "", false
+	}
+	line := content[2:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false
+	}
+	interpreter := fields[0][strings.LastIndexByte(fields[0], '/')+1:]
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	// Versioned interpreters such as python3.12 map to their base name
+	if language, ok := shebangLanguages[interpreter]; ok {
+		return language, true
+	}
+	language, ok := shebangLanguages[strings.TrimRight(interpreter, "0123456789.")]
+	return language, ok
+}
+
+// headerLanguage recognises formats from how their content starts
+func headerLanguage(content string) (string, bool) {
+	lower := strings.ToLower(content)
+	switch {
+	case strings.HasPrefix(content, "<?php"):
+		return "php", true
+	case strings.HasPrefix(content, "<?xml"):
+		return "xml", true
+	case strings.HasPrefix(lower, "<!doctype html"), strings.HasPrefix(lower, "<html"):
+		return "html", true
+	case strings.HasPrefix(content, "diff --git "):
+		return "diff", true
+	case (content[0] == '{' || content[0] == '[') && json.Valid([]byte(content)):
+		return "json", true
+	}
+
+	// Dockerfiles start with FROM once leading comments are skipped
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "FROM ") {
+			return "dockerfile", true
+		}
+		break
+	}
+	return "", false
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"pastebin/models"
+)
+
+func TestLanguageDetectorDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		content string
+		want    string
+	}{
+		{name: "file name in the title", title: "main.go", content: "print('looks like python')", want: "go"},
+		{name: "file name without extension", title: "Dockerfile", content: "RUN make", want: "dockerfile"},
+		{name: "unknown extension falls through", title: "notes.xyz", content: "#!/bin/sh\necho hi", want: "bash"},
+		{name: "shebang", content: "#!/usr/bin/env python3\nprint(1)", want: "python"},
+		{name: "versioned shebang", content: "#!/usr/bin/python3.12\nx = 1", want: "python"},
+		{name: "node shebang", content: "#!/usr/bin/env node\nconsole.log(1)", want: "javascript"},
+		{name: "unknown shebang", content: "#!/usr/bin/awk -f\n{ print }", want: models.LanguagePlaintext},
+		{name: "php header", content: "<?php echo 1;", want: "php"},
+		{name: "xml header", content: "<?xml version=\"1.0\"?><a/>", want: "xml"},
+		{name: "html doctype", content: "<!DOCTYPE html>\n<html></html>", want: "html"},
+		{name: "git diff", content: "diff --git a/x b/x\n--- a/x\n+++ b/x", want: "diff"},
+		{name: "json", content: "{\"a\": [1, 2]}", want: "json"},
+		{name: "invalid json", content: "{not json}", want: models.LanguagePlaintext},
+		{name: "dockerfile after comments", content: "# build\n\nFROM golang:1.24\nRUN go build", want: "dockerfile"},
+		{
+			name:    "go keywords",
+			content: "package main\n\nimport (\n\t\"fmt\"\n)\n\nfunc main() {\n\tif err != nil {\n\t\tfmt.Println(err)\n\t}\n}",
+			want:    "go",
+		},
+		{
+			name:    "python keywords",
+			content: "from os import path\n\nclass A:\n    def run(self):\n        return self.x\n",
+			want:    "python",
+		},
+		{
+			name:    "javascript keywords",
+			content: "const fs = require('fs')\nmodule.exports = () => {\n  console.log(fs)\n}",
+			want:    "javascript",
+		},
+		{name: "prose", content: "Meeting notes: discuss the roadmap and budget.", want: models.LanguagePlaintext},
+		{name: "single weak signal", content: "print(1)", want: models.LanguagePlaintext},
+		{name: "empty", content: "   \n", want: models.LanguagePlaintext},
+	}
+
+	detector := NewLanguageDetector()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detector.Detect(tt.title, tt.content); got != tt.want {
+				t.Errorf("Detect(%q, %q) = %q, want %q", tt.title, tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLanguageDetectorOnlyScansTheStart(t *testing.T) {
+	content := strings.Repeat("x\n", maxDetectionBytes) + "package main\nfunc main() { fmt.Println(err != nil) }\n"
+	if got := NewLanguageDetector().Detect("", content); got != models.LanguagePlaintext {
+		t.Errorf("Detect() = %q, want %q for signals past the scanned prefix", got, models.LanguagePlaintext)
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// markdownClassPattern limits class attributes to the plain names emitted for code highlighting
+var markdownClassPattern = regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)
+
+// MarkdownService renders Markdown pastes to sanitized HTML
+type MarkdownService struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+}
+
+// NewMarkdownService creates a new Markdown service instance
+func NewMarkdownService() *MarkdownService {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+			extension.Strikethrough,
+			extension.Linkify,
+			extension.TaskList,
+			// Fenced code is highlighted with classes, the page includes the theme CSS
+			highlighting.NewHighlighting(
+				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+			),
+		),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+
+	return &MarkdownService{
+		markdown: markdown,
+		policy:   markdownPolicy(),
+	}
+}
+
+// Render converts Markdown to HTML. Raw HTML in the source is dropped by the
+// renderer and the output is sanitized again so a paste can never inject scripts.
+func (s *MarkdownService) Render(content string) (string, error) {
+	var buf bytes.Buffer
+	if err := s.markdown.Convert([]byte(content), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %v", err)
+	}
+	return s.policy.SanitizeReader(&buf).String(), nil
+}
+
+// markdownPolicy allows user generated content plus what GFM tables, task lists and highlighting need
+func markdownPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(markdownClassPattern).OnElements("pre", "code", "span")
+	policy.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	policy.RequireNoReferrerOnLinks(true)
+	return policy
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultOllamaBaseURL is where a local Ollama listens by default
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+// ollamaProvider talks to a local Ollama style /api/chat endpoint
+type ollamaProvider struct {
+	config AIProviderConfig
+}
+
+// newOllamaProvider creates an Ollama style provider
+func newOllamaProvider(config AIProviderConfig) *ollamaProvider {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultOllamaBaseURL
+	}
+	return &ollamaProvider{config: config}
+}
+
+// ollamaChatRequest is the body of an /api/chat request
+type ollamaChatRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Format   string         `json:"format,omitempty"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is the part of a non streaming /api/chat response we use
+type ollamaChatResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// ollamaTagsResponse is the response of /api/tags, which lists the local models
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name       string    `json:"name"`
+		ModifiedAt time.Time `json:"modified_at"`
+	} `json:"models"`
+}
+
+// headers returns the authentication header when a key is configured
+func (p *ollamaProvider) headers() map[string]string {
+	if p.config.APIKey == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + p.config.APIKey}
+}
+
+// Name returns the provider's ai_provider value
+func (p *ollamaProvider) Name() string {
+	return AIProviderOllama
+}
+
+// Generate answers a prompt through the chat API, asking for a JSON answer
+func (p *ollamaProvider) Generate(ctx context.Context, request AIGenerateRequest) (*AIGenerateResult, error) {
+	body := ollamaChatRequest{
+		Model: request.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: request.System},
+			{Role: "user", Content: request.User},
+		},
+		Stream: false,
+		Format: "json",
+		Options: map[string]any{
+			"temperature": request.Temperature,
+			"num_predict": request.MaxTokens,
+		},
+	}
+
+	var response ollamaChatResponse
+	if err := doJSON(ctx, "POST", p.config.BaseURL+"/api/chat", p.headers(), body, &response); err != nil {
+		return nil, fmt.Errorf("failed to call Ollama API: %v", err)
+	}
+	if response.Message.Content == "" {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	return &AIGenerateResult{
+		Text:             response.Message.Content,
+		Model:            response.Model,
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+	}, nil
+}
+
+// ListModels retrieves the locally installed models
+func (p *ollamaProvider) ListModels(ctx context.Context) ([]Model, error) {
+	var response ollamaTagsResponse
+	if err := doJSON(ctx, "GET", p.config.BaseURL+"/api/tags", p.headers(), nil, &response); err != nil {
+		return nil, err
+	}
+
+	models := make([]Model, 0, len(response.Models))
+	for _, model := range response.Models {
+		models = append(models, Model{
+			ID:      model.Name,
+			Object:  "model",
+			Created: unixTime(model.ModifiedAt),
+			OwnedBy: AIProviderOllama,
+		})
+	}
+	return models, nil
+}
+
+// HealthCheck lists the local models, which only needs the server to be up
+func (p *ollamaProvider) HealthCheck(ctx context.Context) error {
+	_, err := p.ListModels(ctx)
+	return err
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+)
+
+// defaultOpenAIBaseURL is the official OpenAI endpoint
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// openAIProvider talks to OpenAI and any API compatible with its chat completions
+type openAIProvider struct {
+	config AIProviderConfig
+	client openai.Client
+}
+
+// newOpenAIProvider creates an OpenAI compatible provider
+func newOpenAIProvider(config AIProviderConfig) *openAIProvider {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultOpenAIBaseURL
+	}
+
+	// Create OpenAI client with custom base URL if provided
+	options := []option.RequestOption{option.WithAPIKey(config.APIKey)}
+	if config.BaseURL != defaultOpenAIBaseURL {
+		options = append(options, option.WithBaseURL(config.BaseURL))
+	}
+
+	return &openAIProvider{
+		config: config,
+		client: openai.NewClient(options...),
+	}
+}
+
+// Name returns the provider's ai_provider value
+func (p *openAIProvider) Name() string {
+	return AIProviderOpenAI
+}
+
+// Generate answers a prompt through the chat completions API
+func (p *openAIProvider) Generate(ctx context.Context, request AIGenerateRequest) (*AIGenerateResult, error) {
+	chatCompletion, err := p.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(request.System),
+			openai.UserMessage(request.User),
+		},
+		Model:           openai.ChatModel(request.Model),
+		MaxTokens:       openai.Int(int64(request.MaxTokens)),
+		Temperature:     openai.Float(request.Temperature),
+		ReasoningEffort: openai.ReasoningEffortLow,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call OpenAI API: %v", err)
+	}
+	if len(chatCompletion.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	return &AIGenerateResult{
+		Text:             chatCompletion.Choices[0].Message.Content,
+		Model:            chatCompletion.Model,
+		PromptTokens:     int(chatCompletion.Usage.PromptTokens),
+		CompletionTokens: int(chatCompletion.Usage.CompletionTokens),
+	}, nil
+}
+
+// ModelsResponse represents the response from models API
+type ModelsResponse struct {
+	Object string  `json:"object"`
+	Data   []Model `json:"data"`
+}
+
+// ListModels retrieves the models from the /models endpoint
+func (p *openAIProvider) ListModels(ctx context.Context) ([]Model, error) {
+	var modelsResp ModelsResponse
+	err := doJSON(ctx, "GET", p.config.BaseURL+"/models", map[string]string{
+		"Authorization": "Bearer " + p.config.APIKey,
+	}, nil, &modelsResp)
+	if err != nil {
+		return nil, err
+	}
+	return modelsResp.Data, nil
+}
+
+// HealthCheck lists the models, which needs a reachable API and a valid key
+func (p *openAIProvider) HealthCheck(ctx context.Context) error {
+	_, err := p.ListModels(ctx)
+	return err
+}
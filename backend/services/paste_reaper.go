@@ -0,0 +1,87 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"pastebin/database"
+)
+
+// PasteReaperService periodically purges expired pastes
+type PasteReaperService struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	running  bool
+	stopChan chan bool
+}
+
+// NewPasteReaperService creates a new paste reaper service
+func NewPasteReaperService() *PasteReaperService {
+	return &PasteReaperService{
+		interval: time.Minute,
+		stopChan: make(chan bool),
+	}
+}
+
+// Start begins the background reaping
+func (s *PasteReaperService) Start() {
+	s.mutex.Lock()
+	if s.running {
+		s.mutex.Unlock()
+		return
+	}
+	s.running = true
+	s.mutex.Unlock()
+
+	log.Println("Paste Reaper Service started")
+
+	go s.reapLoop()
+}
+
+// Stop halts the background reaping
+func (s *PasteReaperService) Stop() {
+	s.mutex.Lock()
+	if !s.running {
+		s.mutex.Unlock()
+		return
+	}
+	s.running = false
+	s.mutex.Unlock()
+
+	log.Println("Stopping Paste Reaper Service...")
+	s.stopChan <- true
+}
+
+// reapLoop runs the main reaping loop
+func (s *PasteReaperService) reapLoop() {
+	// Purge anything that expired while the server was down
+	s.ReapExpiredPastes()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			log.Println("Paste Reaper Service stopped")
+			return
+		case <-ticker.C:
+			s.ReapExpiredPastes()
+		}
+	}
+}
+
+// ReapExpiredPastes deletes expired pastes and returns how many were purged
+func (s *PasteReaperService) ReapExpiredPastes() int64 {
+	purged, err := database.DeleteExpiredPastes()
+	if err != nil {
+		log.Printf("Error purging expired pastes: %v", err)
+		return 0
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d expired pastes", purged)
+	}
+	return purged
+}
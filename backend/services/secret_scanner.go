@@ -0,0 +1,167 @@
+package services
+
+import (
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"pastebin/database"
+	"pastebin/models"
+)
+
+const (
+	// secretRedaction replaces every secret when the redact policy applies
+	secretRedaction = "[REDACTED]"
+	// secretPreviewLength is how many leading characters of a secret are kept in a finding
+	secretPreviewLength = 4
+	// maxPreviewMask caps the number of mask characters in a preview
+	maxPreviewMask = 8
+)
+
+// secretRule is a pattern matching a kind of secret. When the pattern has a capture group,
+// only the group is the secret, e.g. the value of a password assignment.
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+	// minEntropy rejects low entropy matches such as placeholders, 0 accepts every match
+	minEntropy float64
+}
+
+// secretRules are checked in order, a match overlapping an earlier one is dropped,
+// so specific formats come before the generic assignment rule
+var secretRules = []secretRule{
+	{name: "private-key", pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----(?:[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----|[\s\S]*)`)},
+	{name: "aws-access-key-id", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{name: "aws-secret-access-key", pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*(?::=|=>|[:=])\s*["']?([A-Za-z0-9/+=]{40})\b`), minEntropy: 3.5},
+	{name: "jwt", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "github-token", pattern: regexp.MustCompile(`\b(?:(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{name: "gitlab-token", pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`)},
+	{name: "slack-token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{name: "stripe-secret-key", pattern: regexp.MustCompile(`\b(?:sk|rk)_live_[A-Za-z0-9]{20,}\b`)},
+	{name: "google-api-key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{name: "anthropic-api-key", pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`), minEntropy: 3.5},
+	{name: "openai-api-key", pattern: regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`), minEntropy: 3.5},
+	{name: "generic-secret", pattern: regexp.MustCompile("(?i)(?:secret|token|passwd|password|pwd|api[_-]?key|access[_-]?key|auth[_-]?key|credentials?)[A-Za-z0-9_-]*[\"']?\\s*(?::=|=>|[:=])\\s*[\"'`]?([A-Za-z0-9/+_.=~!@#$%^&*-]{16,})"), minEntropy: 3.5},
+}
+
+// SecretScanner detects credentials accidentally included in pastes
+type SecretScanner struct{}
+
+// NewSecretScanner creates a new secret scanner instance
+func NewSecretScanner() *SecretScanner {
+	return &SecretScanner{}
+}
+
+// Scan returns the secrets found in the content in order of appearance
+func (s *SecretScanner) Scan(content string) []models.SecretFinding {
+	findings := []models.SecretFinding{}
+	for _, rule := range secretRules {
+		for _, match := range rule.pattern.FindAllStringSubmatchIndex(content, -1) {
+			start, end := match[0], match[1]
+			if len(match) >= 4 && match[2] >= 0 {
+				start, end = match[2], match[3]
+			}
+			secret := content[start:end]
+			if rule.minEntropy > 0 && !looksRandom(secret, rule.minEntropy) {
+				continue
+			}
+			if overlapsFinding(findings, start, end) {
+				continue
+			}
+			findings = append(findings, models.SecretFinding{
+				Rule:    rule.name,
+				Line:    strings.Count(content[:start], "\n") + 1,
+				Preview: maskSecret(secret),
+				Start:   start,
+				End:     end,
+			})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		return findings[i].Start < findings[j].Start
+	})
+	return findings
+}
+
+// Redact replaces the secrets found by Scan in the same content
+func (s *SecretScanner) Redact(content string, findings []models.SecretFinding) string {
+	var builder strings.Builder
+	last := 0
+	for _, finding := range findings {
+		builder.WriteString(content[last:finding.Start])
+		builder.WriteString(secretRedaction)
+		last = finding.End
+	}
+	builder.WriteString(content[last:])
+	return builder.String()
+}
+
+// RecordSecretFindings stores the secrets found in a paste for review by admins. Blocked
+// pastes were never saved and have no ID. Failing to record is only logged, the paste
+// was already handled according to the policy.
+func RecordSecretFindings(paste *models.Paste, findings []models.SecretFinding) {
+	for i := range findings {
+		if paste.ID != 0 {
+			findings[i].PasteID = &paste.ID
+			findings[i].PasteRandomID = paste.RandomID
+			findings[i].Revision = paste.Revision
+		}
+		findings[i].OwnerID = paste.OwnerID
+	}
+	if err := database.RecordSecretFindings(findings); err != nil {
+		log.Printf("Error recording secret findings of paste %q: %v", paste.RandomID, err)
+	}
+}
+
+// overlapsFinding reports whether the byte range overlaps a secret found before
+func overlapsFinding(findings []models.SecretFinding, start, end int) bool {
+	for _, finding := range findings {
+		if start < finding.End && finding.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// looksRandom reports whether a value has the given Shannon entropy per character and mixes
+// letters and digits, which rules out identifiers, words and placeholders like xxxx
+func looksRandom(value string, minEntropy float64) bool {
+	hasLetter := strings.IndexFunc(value, unicode.IsLetter) >= 0
+	hasDigit := strings.IndexFunc(value, unicode.IsDigit) >= 0
+	return hasLetter && hasDigit && shannonEntropy(value) >= minEntropy
+}
+
+// shannonEntropy returns the entropy of a string in bits per character
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// maskSecret keeps the first characters of a secret for review and masks the rest.
+// Private keys are reduced to their header line.
+func maskSecret(secret string) string {
+	if strings.HasPrefix(secret, "-----BEGIN ") {
+		header := strings.Index(secret[len("-----BEGIN "):], "-----")
+		return secret[:len("-----BEGIN ")+header+len("-----")]
+	}
+
+	keep := min(secretPreviewLength, utf8.RuneCountInString(secret)/4)
+	runes := []rune(secret)
+	return string(runes[:keep]) + strings.Repeat("*", min(len(runes)-keep, maxPreviewMask))
+}
@@ -0,0 +1,276 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"pastebin/models"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Lifetime is how long an issued login token stays valid
+const Lifetime = 24 * time.Hour
+
+// defaultKeysFile is where generated signing keys are persisted
+const defaultKeysFile = "./data/jwt_keys.json"
+
+// ErrKeysFromEnv is returned when rotating keys that are managed through the environment
+var ErrKeysFromEnv = errors.New("JWT keys are managed through the JWT_SECRET environment variable")
+
+// signingKey is a single HMAC key identified by its kid header
+type signingKey struct {
+	KID       string     `json:"kid"`
+	Secret    string     `json:"secret"` // base64 编码
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"` // 轮换后仅用于验证旧令牌
+}
+
+// keySet is the persisted form of all signing keys
+type keySet struct {
+	ActiveKID string       `json:"active_kid"`
+	Keys      []signingKey `json:"keys"`
+}
+
+var (
+	mutex    sync.RWMutex
+	keys     keySet
+	secrets  map[string][]byte
+	keysFile string // empty when keys come from the environment
+)
+
+// UserClaims are the claims carried by a login token
+type UserClaims struct {
+	SessionID string
+	UserID    int
+	Username  string
+	Role      string
+	OAuth2    bool
+}
+
+// Init loads the signing keys. JWT_SECRET (and optionally comma separated
+// JWT_PREVIOUS_SECRETS still accepted for verification) take precedence,
+// otherwise keys are read from JWT_KEYS_FILE, generating it on first start.
+func Init() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		keys = keySet{}
+		keysFile = ""
+		keys.Keys = append(keys.Keys, envKey(secret))
+		keys.ActiveKID = keys.Keys[0].KID
+		for _, previous := range strings.Split(os.Getenv("JWT_PREVIOUS_SECRETS"), ",") {
+			if previous = strings.TrimSpace(previous); previous != "" {
+				keys.Keys = append(keys.Keys, envKey(previous))
+			}
+		}
+		return loadSecrets()
+	}
+
+	keysFile = os.Getenv("JWT_KEYS_FILE")
+	if keysFile == "" {
+		keysFile = defaultKeysFile
+	}
+
+	data, err := os.ReadFile(keysFile)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("No JWT signing key found, generating one in %s", keysFile)
+		keys = keySet{}
+		if err := addKey(); err != nil {
+			return err
+		}
+		return saveKeys()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read JWT keys: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("failed to parse JWT keys: %v", err)
+	}
+	return loadSecrets()
+}
+
+// Rotate generates a new active signing key. Previous keys keep verifying
+// tokens until every token they signed has expired.
+func Rotate() (string, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if keysFile == "" {
+		return "", ErrKeysFromEnv
+	}
+
+	now := time.Now()
+	var kept []signingKey
+	for _, key := range keys.Keys {
+		if key.RetiredAt == nil {
+			key.RetiredAt = &now
+		}
+		// Drop keys that can no longer have valid tokens
+		if now.Sub(*key.RetiredAt) < Lifetime {
+			kept = append(kept, key)
+		}
+	}
+	keys.Keys = kept
+
+	if err := addKey(); err != nil {
+		return "", err
+	}
+	if err := saveKeys(); err != nil {
+		return "", err
+	}
+	return keys.ActiveKID, nil
+}
+
+// Sign signs the claims with the active key, setting the kid header
+func Sign(claims jwt.MapClaims) (string, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	secret, ok := secrets[keys.ActiveKID]
+	if !ok {
+		return "", errors.New("no active JWT signing key")
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t.Header["kid"] = keys.ActiveKID
+	return t.SignedString(secret)
+}
+
+// Parse validates a token against the key named by its kid header and returns its claims
+func Parse(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		mutex.RLock()
+		defer mutex.RUnlock()
+		secret, ok := secrets[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// NewSessionID generates a random ID for a login session, used as the token's jti
+func NewSessionID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// NewUserToken issues a login token for the given user bound to a server-side session
+func NewUserToken(user *models.User, sessionID string) (string, error) {
+	return Sign(jwt.MapClaims{
+		"jti":      sessionID,
+		"user_id":  user.ID,
+		"username": user.Username,
+		"role":     user.Role,
+		"oauth2":   user.Provider == models.UserProviderOAuth2,
+		"exp":      time.Now().Add(Lifetime).Unix(),
+	})
+}
+
+// ParseUserToken validates a login token and extracts the user claims
+func ParseUserToken(tokenString string) (*UserClaims, error) {
+	claims, err := Parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens issued before user accounts existed carry no user ID
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("token has no user")
+	}
+	sessionID, _ := claims["jti"].(string)
+	if sessionID == "" {
+		return nil, errors.New("token has no session")
+	}
+
+	userClaims := &UserClaims{SessionID: sessionID, UserID: int(userID)}
+	userClaims.Username, _ = claims["username"].(string)
+	userClaims.Role, _ = claims["role"].(string)
+	userClaims.OAuth2, _ = claims["oauth2"].(bool)
+	return userClaims, nil
+}
+
+// envKey builds a key from a secret given in the environment, its kid is derived from the secret
+func envKey(secret string) signingKey {
+	sum := sha256.Sum256([]byte(secret))
+	return signingKey{
+		KID:    hex.EncodeToString(sum[:])[:8],
+		Secret: base64.StdEncoding.EncodeToString([]byte(secret)),
+	}
+}
+
+// addKey generates a random key and makes it the active one
+func addKey() error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	kid := make([]byte, 4)
+	if _, err := rand.Read(kid); err != nil {
+		return err
+	}
+
+	key := signingKey{
+		KID:       hex.EncodeToString(kid),
+		Secret:    base64.StdEncoding.EncodeToString(secret),
+		CreatedAt: time.Now(),
+	}
+	keys.Keys = append(keys.Keys, key)
+	keys.ActiveKID = key.KID
+	return loadSecrets()
+}
+
+// loadSecrets decodes the key set into the lookup table used for signing and verification
+func loadSecrets() error {
+	decoded := make(map[string][]byte, len(keys.Keys))
+	for _, key := range keys.Keys {
+		secret, err := base64.StdEncoding.DecodeString(key.Secret)
+		if err != nil {
+			return fmt.Errorf("invalid JWT key %q: %v", key.KID, err)
+		}
+		decoded[key.KID] = secret
+	}
+	if _, ok := decoded[keys.ActiveKID]; !ok {
+		return fmt.Errorf("active JWT key %q not found", keys.ActiveKID)
+	}
+
+	secrets = decoded
+	return nil
+}
+
+// saveKeys persists the key set readable by the owner only
+func saveKeys() error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keysFile), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(keysFile, data, 0o600)
+}
@@ -6,6 +6,7 @@ import toast from 'react-hot-toast'
 const AISettings = () => {
   const [config, setConfig] = useState({
     enabled: false,
+    provider: 'openai',
     base_url: '',
     api_key: '',
     model: 'gpt-3.5-turbo',
@@ -18,6 +19,13 @@ const AISettings = () => {
   const [fetchingModels, setFetchingModels] = useState(false)
   const [availableModels, setAvailableModels] = useState([])
 
+  const providers = [
+    { id: 'openai', name: 'OpenAI 兼容', baseURL: 'https://api.openai.com/v1', keyHint: 'sk-...' },
+    { id: 'anthropic', name: 'Anthropic', baseURL: 'https://api.anthropic.com/v1', keyHint: 'sk-ant-...' },
+    { id: 'ollama', name: 'Ollama', baseURL: 'http://localhost:11434', keyHint: '本地 Ollama 无需 API Key' }
+  ]
+  const currentProvider = providers.find(p => p.id === config.provider) || providers[0]
+
   useEffect(() => {
     loadConfig()
   }, [])
@@ -166,6 +174,27 @@ const AISettings = () => {
           </label>
         </motion.div>
 
+        {/* Provider Selection */}
+        <motion.div
+          initial={{ opacity: 0, y: 20 }}
+          animate={{ opacity: 1, y: 0 }}
+          transition={{ duration: 0.3, delay: 0.05 }}
+        >
+          <label className="block text-sm font-semibold text-gray-700 mb-2">
+            AI 提供方
+          </label>
+          <select
+            value={config.provider}
+            onChange={(e) => updateConfig('provider', e.target.value)}
+            className="w-full px-4 py-3 rounded-xl backdrop-blur-md bg-white/20 border border-white/30 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 transition-all duration-300"
+          >
+            {providers.map(provider => (
+              <option key={provider.id} value={provider.id}>{provider.name}</option>
+            ))}
+          </select>
+          <p className="text-xs text-gray-500 mt-1">切换提供方后请同时修改 API Base URL，留空则使用默认地址</p>
+        </motion.div>
+
         {/* API Configuration */}
         <div className="grid grid-cols-1 md:grid-cols-2 gap-6">
           <motion.div
@@ -181,9 +210,9 @@ const AISettings = () => {
               value={config.base_url}
               onChange={(e) => updateConfig('base_url', e.target.value)}
               className="w-full px-4 py-3 rounded-xl backdrop-blur-md bg-white/20 border border-white/30 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 transition-all duration-300 placeholder-gray-500"
-              placeholder="https://api.openai.com/v1"
+              placeholder={currentProvider.baseURL}
             />
-            <p className="text-xs text-gray-500 mt-1">支持 OpenAI 兼容的 API 接口</p>
+            <p className="text-xs text-gray-500 mt-1">默认：{currentProvider.baseURL}</p>
           </motion.div>
 
           <motion.div
@@ -199,7 +228,7 @@ const AISettings = () => {
               value={config.api_key}
               onChange={(e) => updateConfig('api_key', e.target.value)}
               className="w-full px-4 py-3 rounded-xl backdrop-blur-md bg-white/20 border border-white/30 focus:border-blue-500 focus:ring-2 focus:ring-blue-500/20 transition-all duration-300 placeholder-gray-500"
-              placeholder="sk-..."
+              placeholder={currentProvider.keyHint}
             />
             <p className="text-xs text-gray-500 mt-1">请妥善保管您的 API Key</p>
           </motion.div>
@@ -1,11 +1,37 @@
 import React, { useState } from 'react'
 import { motion } from 'framer-motion'
-import { Type, FileText, Send, Sparkles } from 'lucide-react'
+import { Type, FileText, Send, Sparkles, Lock } from 'lucide-react'
 import toast from 'react-hot-toast'
 
+// Base64 helpers matching the decoding in view.js
+const toBase64 = (bytes) => {
+  let binary = ''
+  for (let i = 0; i < bytes.length; i += 0x8000) {
+    binary += String.fromCharCode(...bytes.subarray(i, i + 0x8000))
+  }
+  return btoa(binary)
+}
+const toBase64Url = (bytes) => toBase64(bytes).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '')
+
+// Encrypt content with a new AES-256-GCM key. The key never leaves the browser,
+// it is only put in the URL fragment of the share link.
+const encryptContent = async (plaintext) => {
+  const key = await crypto.subtle.generateKey({ name: 'AES-GCM', length: 256 }, true, ['encrypt'])
+  const iv = crypto.getRandomValues(new Uint8Array(12))
+  const ciphertext = await crypto.subtle.encrypt({ name: 'AES-GCM', iv }, key, new TextEncoder().encode(plaintext))
+
+  // content 为 12 字节 IV 加上密文和认证标签
+  const payload = new Uint8Array(iv.length + ciphertext.byteLength)
+  payload.set(iv)
+  payload.set(new Uint8Array(ciphertext), iv.length)
+  const keyData = new Uint8Array(await crypto.subtle.exportKey('raw', key))
+  return { content: toBase64(payload), key: toBase64Url(keyData) }
+}
+
 const PasteForm = ({ onPasteCreated }) => {
   const [title, setTitle] = useState('')
   const [content, setContent] = useState('')
+  const [encrypted, setEncrypted] = useState(false)
   const [isLoading, setIsLoading] = useState(false)
 
   const handleSubmit = async (e) => {
@@ -16,15 +42,34 @@ const PasteForm = ({ onPasteCreated }) => {
       return
     }
 
+    if (encrypted && !window.crypto?.subtle) {
+      toast.error('当前浏览器不支持加密，请通过 HTTPS 访问')
+      return
+    }
+
     setIsLoading(true)
     try {
+      let body = { title: title.trim(), content: content.trim() }
+      let key = ''
+      if (encrypted) {
+        const result = await encryptContent(body.content)
+        key = result.key
+        body = {
+          ...body,
+          content: result.content,
+          encrypted: true,
+          encryption_format: 'aes-256-gcm',
+          encryption_version: 1
+        }
+      }
+
       const response = await fetch('/api/paste', {
         method: 'POST',
         headers: {
           'Content-Type': 'application/json'
         },
         credentials: 'include',
-        body: JSON.stringify({ title: title.trim(), content: content.trim() })
+        body: JSON.stringify(body)
       })
       
       const data = await response.json()
@@ -33,6 +78,7 @@ const PasteForm = ({ onPasteCreated }) => {
         const url = new URL(window.location)
         url.pathname = '/' + data.random_id
         url.search = ''
+        url.hash = key
         
         onPasteCreated({
           ...data,
@@ -123,6 +169,24 @@ const PasteForm = ({ onPasteCreated }) => {
             />
           </motion.div>
 
+          {/* Encryption Option */}
+          <motion.div
+            initial={{ opacity: 0, x: -20 }}
+            animate={{ opacity: 1, x: 0 }}
+            transition={{ duration: 0.5, delay: 0.35 }}
+          >
+            <label className="flex items-center space-x-3 text-sm text-gray-700 cursor-pointer">
+              <input
+                type="checkbox"
+                checked={encrypted}
+                onChange={(e) => setEncrypted(e.target.checked)}
+                className="h-4 w-4 rounded border-white/30 text-blue-500 focus:ring-blue-500/20"
+              />
+              <Lock className="h-4 w-4" />
+              <span>端到端加密 (密钥只保存在分享链接中，丢失链接将无法解密)</span>
+            </label>
+          </motion.div>
+
           {/* Submit Button */}
           <motion.div
             initial={{ opacity: 0, y: 20 }}
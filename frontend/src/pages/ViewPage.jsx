@@ -6,7 +6,8 @@ const ViewPage = () => {
 
   // 由于view页面是独立的，我们直接重定向到原有的view页面
   React.useEffect(() => {
-    window.location.href = `/${id}`
+    // 保留 URL 片段，加密内容的密钥在其中
+    window.location.href = `/${id}${window.location.hash}`
   }, [id])
 
   return (
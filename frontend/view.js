@@ -10,6 +10,7 @@ const loading = document.getElementById('loading');
 // 语法高亮状态 - 默认启用语法高亮
 let isHighlightMode = true;
 let currentPasteContent = '';
+let currentPasteLanguage = '';
 
 // 滚动处理函数
 let handleScrollFn = null;
@@ -22,16 +23,36 @@ if (pasteId && pasteId !== '') {
 }
 
 // Fetch paste data
-async function fetchPaste(id) {
+async function fetchPaste(id, password) {
     try {
         showLoading();
+        const headers = password ? { 'X-Paste-Password': password } : {};
         const response = await fetch(`/api/paste/${id}`, {
-            credentials: 'include'
+            credentials: 'include',
+            headers
         });
         const data = await response.json();
         
         if (response.ok) {
+            if (data.encrypted) {
+                try {
+                    data.content = await decryptPasteContent(data);
+                } catch (error) {
+                    console.error('Error decrypting paste:', error);
+                    showError('解密失败，请检查链接中的密钥是否完整');
+                    return;
+                }
+            }
             displayPaste(data);
+        } else if (data.password_required && response.status === 401) {
+            // Ask for the password and try again
+            const message = password ? '密码错误，请重新输入访问密码' : '该内容受密码保护，请输入访问密码';
+            const input = window.prompt(message);
+            if (input) {
+                fetchPaste(id, input);
+            } else {
+                showError(data.error);
+            }
         } else {
             showError(data.error || '获取内容失败');
         }
@@ -41,6 +62,21 @@ async function fetchPaste(id) {
     }
 }
 
+// Decrypt an end-to-end encrypted paste with the key kept in the URL fragment
+async function decryptPasteContent(data) {
+    if (data.encryption_format !== 'aes-256-gcm' || data.encryption_version !== 1) {
+        throw new Error('不支持的加密格式');
+    }
+    const fromBase64 = (value) => Uint8Array.from(atob(value.replace(/-/g, '+').replace(/_/g, '/')), (c) => c.charCodeAt(0));
+    const keyData = fromBase64(window.location.hash.slice(1));
+    const payload = fromBase64(data.content);
+
+    // content 为 12 字节 IV 加上密文和认证标签
+    const key = await crypto.subtle.importKey('raw', keyData, 'AES-GCM', false, ['decrypt']);
+    const plaintext = await crypto.subtle.decrypt({ name: 'AES-GCM', iv: payload.slice(0, 12) }, key, payload.slice(12));
+    return new TextDecoder().decode(plaintext);
+}
+
 // Display paste content
 function displayPaste(data) {
     hideLoading();
@@ -75,10 +111,13 @@ function displayPaste(data) {
         // 隐藏AI标签
         aiTagElement.style.display = 'none';
     }
+    // AI 生成的简介作为标题的提示文字
+    pasteTitleElement.title = data.description || '';
     document.getElementById('pasteDate').textContent = formatRelativeTime(data.created_at);
     
     // 保存内容
     currentPasteContent = data.content;
+    currentPasteLanguage = data.language || '';
     
     // 自动检测是否为日志内容
     if (isLogContent(data.content)) {
@@ -310,7 +349,10 @@ function renderHighlightedContent(content) {
     pasteContentElement.innerHTML = '';
     pasteContentElement.appendChild(codeElement);
     
-    // 自动检测语言并应用高亮
+    // 使用服务端识别的语言，未知时由 highlight.js 自动检测
+    if (currentPasteLanguage && currentPasteLanguage !== 'plaintext' && hljs.getLanguage(currentPasteLanguage)) {
+        codeElement.classList.add(`language-${currentPasteLanguage}`);
+    }
     hljs.highlightElement(codeElement);
     
     // 添加行号